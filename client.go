@@ -0,0 +1,323 @@
+package profilego
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	otelpyroscope "github.com/grafana/otel-profiling-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+	"github.com/wasilak/profilego/manager"
+	profiler_pkg "github.com/wasilak/profilego/profiler"
+)
+
+// Client is an independent profiling instance with its own profilers and tagger.
+// Several clients can be used in the same binary, e.g. by different libraries.
+// All methods are safe for concurrent use.
+type Client struct {
+	mu      sync.RWMutex
+	manager *manager.ProfilerManager
+	tagger  core.Tagger
+	ctx     context.Context
+	// enforcer applies tagPolicy, kept while the policy is unchanged so that
+	// the values seen per key and the counters carry over configuration changes
+	enforcer  *profiler_pkg.TagPolicyEnforcer
+	tagPolicy *core.TagPolicy
+}
+
+// New creates a client for the given configuration and initializes its profilers,
+// starting them if InitialState is enabled. If ctx is nil, context.Background() is used.
+func New(ctx context.Context, cfg config.Config) (*Client, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	c := &Client{ctx: ctx}
+	enforcer := c.tagEnforcer(cfg.TagPolicy)
+	cfg = enforceStaticTags(enforcer, cfg)
+	c.manager = manager.NewProfilerManager(cfg)
+	c.updateTagger(cfg, enforcer)
+
+	if err := c.manager.Init(ctx); err != nil {
+		return nil, err
+	}
+
+	// Wrap and register OTel TracerProvider if provided and using Pyroscope backend
+	if cfg.OTelTracerProvider != nil && usesBackend(cfg, core.PyroscopeBackend) {
+		if err := c.handleOTelTracerProvider(ctx, cfg.OTelTracerProvider); err != nil {
+			c.manager.Stop(context.WithoutCancel(ctx))
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Context returns the context the client was created with
+func (c *Client) Context() context.Context {
+	return c.ctx
+}
+
+// Start starts all profilers, also after Stop
+func (c *Client) Start(ctx context.Context) error {
+	return c.manager.Start(ctx)
+}
+
+// Stop stops all profilers gracefully, waiting for final uploads until ctx is done or the
+// configured Timeout elapses. A backend that fails to flush in time is reported with a *core.TimeoutError.
+func (c *Client) Stop(ctx context.Context) error {
+	return c.manager.Stop(ctx)
+}
+
+// Pause temporarily stops all profilers
+func (c *Client) Pause(ctx context.Context) error {
+	return c.manager.Pause(ctx)
+}
+
+// Resume resumes all profilers after Pause
+func (c *Client) Resume(ctx context.Context) error {
+	return c.manager.Resume(ctx)
+}
+
+// IsRunning returns whether profiling is currently active, i.e. started and not paused
+func (c *Client) IsRunning() bool {
+	return c.manager.IsRunning()
+}
+
+// State returns the lifecycle state of the client
+func (c *Client) State() core.State {
+	return c.manager.State()
+}
+
+// States returns the lifecycle state of every profiler by name
+func (c *Client) States() map[string]core.State {
+	return c.manager.States()
+}
+
+// Status returns a snapshot of the client's profilers, including automatic restart attempts
+// and whether profiling runs degraded
+func (c *Client) Status() manager.Status {
+	return c.manager.Status()
+}
+
+// Stats returns the estimated overhead of profiling per backend and the reductions applied
+// to stay within OverheadBudgetPercent
+func (c *Client) Stats() manager.Stats {
+	return c.manager.Stats()
+}
+
+// OnStateChange registers a listener called after every profiler state change, e.g. to log,
+// alert or report readiness. Listeners are called synchronously and must not call back into the client.
+func (c *Client) OnStateChange(listener core.StateListener) {
+	c.manager.OnStateChange(listener)
+}
+
+// OnMemoryEvent registers a listener called after profile types are suspended or resumed
+// because of MemoryLimitMB. Listeners are called synchronously and must not call back into the client.
+func (c *Client) OnMemoryEvent(listener manager.MemoryListener) {
+	c.manager.OnMemoryEvent(listener)
+}
+
+// ApplyConfig replaces the profiling configuration at runtime, restarting only the
+// profilers affected by the change
+func (c *Client) ApplyConfig(cfg config.Config) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The manager keeps the current policy when cfg does not set one
+	if cfg.TagPolicy == nil {
+		cfg.TagPolicy = c.tagPolicy
+	}
+	enforcer := c.tagEnforcer(cfg.TagPolicy)
+	if err := c.manager.ApplyConfig(enforceStaticTags(enforcer, cfg)); err != nil {
+		return err
+	}
+
+	c.updateTagger(c.manager.Config(), enforcer)
+	return nil
+}
+
+// WatchConfig reloads the configuration with load every interval and applies it when it changes.
+// It runs in the background until ctx is cancelled.
+func (c *Client) WatchConfig(ctx context.Context, interval time.Duration, load manager.ConfigLoader) {
+	go c.manager.WatchFunc(ctx, interval, load, c.ApplyConfig)
+}
+
+// AddTag adds a key-value pair tag to the labels of ctx and sets them on the calling goroutine,
+// so the tag applies to its remaining work and the goroutines it starts.
+// The returned context carries the tag; pass it on so later tags are added to it.
+// If ctx is nil, the client context is used
+func (c *Client) AddTag(ctx context.Context, key, value string) (context.Context, error) {
+	if ctx == nil {
+		ctx = c.ctx
+	}
+	return c.currentTagger().AddTag(ctx, key, value)
+}
+
+// AddTagScoped adds a tag like AddTag and returns a release function restoring the labels
+// of ctx on the calling goroutine, e.g. deferred at the start of a request
+// If ctx is nil, the client context is used
+func (c *Client) AddTagScoped(ctx context.Context, key, value string) (context.Context, func(), error) {
+	if ctx == nil {
+		ctx = c.ctx
+	}
+	return c.currentTagger().AddTagScoped(ctx, key, value)
+}
+
+// TagWrapper executes a function with additional profiling tags
+// The tags are only applied during the execution of the function
+// If ctx is nil, the client context is used
+func (c *Client) TagWrapper(ctx context.Context, key, value string, fn func(context.Context) error) error {
+	if ctx == nil {
+		ctx = c.ctx
+	}
+	return c.currentTagger().TagWrapper(ctx, key, value, fn)
+}
+
+// TagWrapperLabels executes a function with several additional profiling tags at once,
+// merged with the tags already present in ctx. The tags are only applied during the execution of the function.
+// If ctx is nil, the client context is used
+func (c *Client) TagWrapperLabels(ctx context.Context, tags core.Tags, fn func(context.Context) error) error {
+	if ctx == nil {
+		ctx = c.ctx
+	}
+	return c.currentTagger().TagWrapperLabels(ctx, tags, fn)
+}
+
+// SetGlobalTag adds or replaces a static tag at runtime, e.g. a feature flag set or a leader/follower role.
+// The Pyroscope backend applies it to subsequent uploads without restarting.
+// The TagPolicy applies as to other tags; a tag it drops is not set.
+func (c *Client) SetGlobalTag(key, value string) error {
+	if err := config.ValidateTagKey(key); err != nil {
+		return err
+	}
+	if err := config.ValidateTagValue(value); err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	enforcer := c.enforcer
+	c.mu.RUnlock()
+
+	if enforcer != nil {
+		tags := enforcer.Apply(core.NewTags(key, value))
+		if len(tags) == 0 {
+			return nil
+		}
+		value = tags[0].Value
+	}
+	return c.manager.SetGlobalTag(key, value)
+}
+
+// RemoveGlobalTag removes a static tag, configured or set at runtime, from subsequent uploads
+func (c *Client) RemoveGlobalTag(key string) error {
+	return c.manager.RemoveGlobalTag(key)
+}
+
+// TagStats returns the number of tags dropped or collapsed by the TagPolicy so far, per key
+func (c *Client) TagStats() core.TagPolicyStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.enforcer == nil {
+		return core.TagPolicyStats{}
+	}
+	return c.enforcer.Stats()
+}
+
+// tagEnforcer returns the enforcer for policy, the current one while the policy is unchanged
+// so that the values seen per key and the counters carry over configuration changes
+// Must be called with c.mu held
+func (c *Client) tagEnforcer(policy *core.TagPolicy) *profiler_pkg.TagPolicyEnforcer {
+	if policy == c.tagPolicy {
+		return c.enforcer
+	}
+	if policy == nil {
+		return nil
+	}
+	return profiler_pkg.NewTagPolicyEnforcer(*policy)
+}
+
+// updateTagger creates the tagger for cfg, applying tags through enforcer if set
+// Must be called with c.mu held
+func (c *Client) updateTagger(cfg config.Config, enforcer *profiler_pkg.TagPolicyEnforcer) {
+	c.tagPolicy, c.enforcer = cfg.TagPolicy, enforcer
+
+	c.tagger = newTagger(cfg)
+	if enforcer != nil {
+		c.tagger = profiler_pkg.NewPolicyTagger(c.tagger, enforcer)
+	}
+}
+
+// enforceStaticTags returns cfg with the static tags of every backend passed through enforcer
+func enforceStaticTags(enforcer *profiler_pkg.TagPolicyEnforcer, cfg config.Config) config.Config {
+	if enforcer == nil {
+		return cfg
+	}
+
+	cfg.Tags = enforcer.ApplyMap(cfg.Tags)
+	cfg.Backends = slices.Clone(cfg.Backends)
+	for i := range cfg.Backends {
+		cfg.Backends[i].Config.Tags = enforcer.ApplyMap(cfg.Backends[i].Config.Tags)
+	}
+	return cfg
+}
+
+// currentTagger returns the tagger for the current configuration
+func (c *Client) currentTagger() core.Tagger {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tagger
+}
+
+// WrapTracerProvider wraps a standard TracerProvider with Pyroscope profiling
+// when a Pyroscope backend is configured. For other backends, returns the original provider.
+func (c *Client) WrapTracerProvider(ctx context.Context, tp trace.TracerProvider) (trace.TracerProvider, error) {
+	for _, b := range c.manager.Config().EffectiveBackends() {
+		if b.Type != core.PyroscopeBackend {
+			continue
+		}
+
+		// Wrap with Pyroscope OTel integration
+		return otelpyroscope.NewTracerProvider(
+			tp,
+			otelpyroscope.WithAppName(b.Config.ApplicationName),
+			otelpyroscope.WithPyroscopeURL(b.Config.ServerAddress),
+		), nil
+	}
+
+	return tp, nil // Other backends don't need wrapping
+}
+
+// SetTracerProvider wraps and registers a TracerProvider globally.
+// This is a convenience function combining WrapTracerProvider and otel.SetTracerProvider.
+func (c *Client) SetTracerProvider(ctx context.Context, tp trace.TracerProvider) error {
+	wrappedTP, err := c.WrapTracerProvider(ctx, tp)
+	if err != nil {
+		return err
+	}
+	otel.SetTracerProvider(wrappedTP)
+	return nil
+}
+
+// handleOTelTracerProvider handles OTel integration if provided in config.
+// The tp parameter should be a trace.TracerProvider instance.
+func (c *Client) handleOTelTracerProvider(ctx context.Context, tp interface{}) error {
+	// Type assert to trace.TracerProvider
+	tracerProvider, ok := tp.(trace.TracerProvider)
+	if !ok {
+		return fmt.Errorf("OTelTracerProvider is not a valid trace.TracerProvider")
+	}
+
+	return c.SetTracerProvider(ctx, tracerProvider)
+}
+
+// errNotInitialized is returned by the package-level functions before InitWithConfig
+var errNotInitialized = errors.New("profiler not initialized - call InitWithConfig first")
@@ -0,0 +1,348 @@
+package profilego
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+// newTestClient creates a client with a pprof backend writing into a temporary directory
+func newTestClient(t *testing.T, app string) *Client {
+	t.Helper()
+
+	client, err := New(context.Background(), config.Config{
+		ApplicationName: app,
+		Backend:         core.PprofBackend,
+		ProfileTypes:    []core.ProfileType{core.ProfileGoroutines},
+		InitialState:    core.ProfilingEnabled,
+		OutputDir:       t.TempDir(),
+		MemoryLimitMB:   1024,
+	})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	t.Cleanup(func() { client.Stop(context.Background()) })
+	return client
+}
+
+// TestClientsAreIndependent tests that clients in the same binary do not share state
+func TestClientsAreIndependent(t *testing.T) {
+	first := newTestClient(t, "first")
+	second := newTestClient(t, "second")
+
+	if !first.IsRunning() || !second.IsRunning() {
+		t.Fatal("Both clients should be running")
+	}
+
+	if err := first.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+	if first.IsRunning() {
+		t.Error("Stopped client should not be running")
+	}
+	if !second.IsRunning() {
+		t.Error("Stopping one client should not affect another")
+	}
+
+	if _, err := second.AddTag(context.Background(), "key", "value"); err != nil {
+		t.Errorf("AddTag returned error: %v", err)
+	}
+}
+
+// TestClientPauseResume tests pausing and resuming a client
+func TestClientPauseResume(t *testing.T) {
+	client := newTestClient(t, "paused")
+
+	if err := client.Pause(context.Background()); err != nil {
+		t.Fatalf("Pause returned error: %v", err)
+	}
+	if err := client.Resume(context.Background()); err != nil {
+		t.Fatalf("Resume returned error: %v", err)
+	}
+}
+
+// TestClientTagWrapperDefaultsContext tests that a nil context falls back to the client context
+func TestClientTagWrapperDefaultsContext(t *testing.T) {
+	type contextKey struct{}
+	client, err := New(context.WithValue(context.Background(), contextKey{}, "client"), config.Config{
+		ApplicationName: "tagged",
+		Backend:         core.PprofBackend,
+		ProfileTypes:    []core.ProfileType{core.ProfileGoroutines},
+		OutputDir:       t.TempDir(),
+		MemoryLimitMB:   1024,
+	})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	defer client.Stop(context.Background())
+
+	var received context.Context
+	err = client.TagWrapper(nil, "key", "value", func(c context.Context) error {
+		received = c
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TagWrapper returned error: %v", err)
+	}
+	if received.Value(contextKey{}) != "client" {
+		t.Error("Expected the client context to be passed to the function")
+	}
+	if value, _ := pprof.Label(received, "key"); value != "value" {
+		t.Errorf("Expected the context to carry the pprof label, got %q", value)
+	}
+}
+
+// TestClientTagPolicy tests that the tag policy applies to tags and carries over configuration changes
+func TestClientTagPolicy(t *testing.T) {
+	cfg := config.Config{
+		ApplicationName: "policy",
+		Backend:         core.PprofBackend,
+		ProfileTypes:    []core.ProfileType{core.ProfileGoroutines},
+		InitialState:    core.ProfilingEnabled,
+		OutputDir:       t.TempDir(),
+		MemoryLimitMB:   1024,
+		TagPolicy:       &core.TagPolicy{MaxValuesPerKey: 1, DenyKeys: []string{"session"}},
+	}
+	client, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	defer client.Stop(context.Background())
+	defer pprof.SetGoroutineLabels(context.Background())
+
+	client.AddTag(context.Background(), "user_id", "1")
+	client.AddTag(context.Background(), "session", "abc")
+
+	// ApplyConfig keeps the policy, and the values seen, when cfg does not set one
+	cfg.TagPolicy = nil
+	if err := client.ApplyConfig(cfg); err != nil {
+		t.Fatalf("ApplyConfig returned error: %v", err)
+	}
+
+	ctx, err := client.AddTag(context.Background(), "user_id", "2")
+	if err != nil {
+		t.Fatalf("AddTag returned error: %v", err)
+	}
+	if value, _ := pprof.Label(ctx, "user_id"); value != core.DefaultOverflowValue {
+		t.Errorf("Expected the second user_id to collapse into %q, got %q", core.DefaultOverflowValue, value)
+	}
+
+	stats := client.TagStats()
+	if stats.Dropped["session"] != 1 || stats.Collapsed["user_id"] != 1 {
+		t.Errorf("Expected one dropped and one collapsed tag, got %+v", stats)
+	}
+}
+
+// TestClientTagPolicyStaticTags tests that the tag policy applies to the configured tags and to global tags
+func TestClientTagPolicyStaticTags(t *testing.T) {
+	var mu sync.Mutex
+	var names []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		names = append(names, r.URL.Query().Get("name"))
+	}))
+	defer server.Close()
+
+	client, err := New(context.Background(), config.Config{
+		ApplicationName: "policy",
+		Backend:         core.PyroscopeBackend,
+		ServerAddress:   server.URL,
+		ProfileTypes:    []core.ProfileType{core.ProfileCPU},
+		InitialState:    core.ProfilingEnabled,
+		MemoryLimitMB:   1024,
+		Tags:            map[string]string{"env": "PROD", "host": "web-1"},
+		TagPolicy: &core.TagPolicy{
+			MaxValues:   map[string]int{"region": 1},
+			DenyKeys:    []string{"host"},
+			Normalizers: map[string][]core.TagNormalizer{"env": {core.Lowercase}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	if tags := client.manager.Config().Tags; len(tags) != 1 || tags["env"] != "prod" {
+		t.Errorf("Expected the configured tags filtered and normalized, got %v", tags)
+	}
+
+	for _, tag := range [][2]string{{"region", "eu"}, {"region", "us"}, {"host", "web-2"}} {
+		if err := client.SetGlobalTag(tag[0], tag[1]); err != nil {
+			t.Fatalf("SetGlobalTag(%q, %q) returned error: %v", tag[0], tag[1], err)
+		}
+	}
+	// A comma would split the value into another tag in the application name
+	var configErr *config.ConfigError
+	if err := client.SetGlobalTag("team", "a,b"); !errors.As(err, &configErr) {
+		t.Errorf("Expected *config.ConfigError for a tag value with a comma, got %v", err)
+	}
+
+	// Stopping uploads the profiles collected so far
+	if err := client.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(names) == 0 {
+		t.Fatal("Expected profiles to be uploaded on Stop")
+	}
+	for _, name := range names {
+		if !strings.Contains(name, "env=prod") || !strings.Contains(name, "region="+core.DefaultOverflowValue) || strings.Contains(name, "host=") {
+			t.Errorf("Expected the uploads to carry the tags allowed by the policy, got %q", name)
+		}
+	}
+
+	stats := client.TagStats()
+	if stats.Dropped["host"] != 2 || stats.Collapsed["region"] != 1 {
+		t.Errorf("Expected two dropped host tags and one collapsed region, got %+v", stats)
+	}
+}
+
+// TestPackageFunctionsAreConcurrencySafe tests the package-level wrappers under concurrent use
+func TestPackageFunctionsAreConcurrencySafe(t *testing.T) {
+	cfg := config.Config{
+		ApplicationName: "test-app",
+		Backend:         core.PprofBackend,
+		ProfileTypes:    []core.ProfileType{core.ProfileGoroutines},
+		OutputDir:       t.TempDir(),
+		MemoryLimitMB:   1024,
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := InitWithConfig(context.Background(), cfg); err != nil {
+				t.Errorf("InitWithConfig returned error: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			AddTag(context.Background(), "key", "value")
+			IsRunning()
+			Context()
+		}()
+	}
+	wg.Wait()
+
+	if err := Stop(); err != nil {
+		t.Errorf("Stop returned error: %v", err)
+	}
+}
+
+// TestPackageLifecycle tests pausing, resuming and restarting through the package-level functions
+func TestPackageLifecycle(t *testing.T) {
+	defaultClient = nil
+	if err := Pause(); err == nil {
+		t.Error("Pause should fail before InitWithConfig")
+	}
+
+	_, err := InitWithConfig(context.Background(), config.Config{
+		ApplicationName: "test-app",
+		Backend:         core.PprofBackend,
+		ProfileTypes:    []core.ProfileType{core.ProfileGoroutines},
+		InitialState:    core.ProfilingEnabled,
+		OutputDir:       t.TempDir(),
+		MemoryLimitMB:   1024,
+	})
+	if err != nil {
+		t.Fatalf("InitWithConfig returned error: %v", err)
+	}
+	defer Stop()
+
+	if err := Pause(); err != nil {
+		t.Fatalf("Pause returned error: %v", err)
+	}
+	if IsRunning() || States()["pprof"] != core.StatePaused {
+		t.Errorf("Expected paused profiling, got %v", States())
+	}
+
+	if err := Resume(); err != nil {
+		t.Fatalf("Resume returned error: %v", err)
+	}
+	if !IsRunning() || States()["pprof"] != core.StateRunning {
+		t.Errorf("Expected running profiling, got %v", States())
+	}
+
+	if err := Stop(); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+	if err := Start(); err != nil {
+		t.Fatalf("Start after Stop returned error: %v", err)
+	}
+	if !IsRunning() {
+		t.Error("Profiling should be running again after Start")
+	}
+}
+
+// TestClientOnStateChange tests that profiler state changes reach the client listeners
+func TestClientOnStateChange(t *testing.T) {
+	client := newTestClient(t, "observed")
+
+	var changes []core.State
+	client.OnStateChange(func(name string, from, to core.State, err error) {
+		if name == "pprof" {
+			changes = append(changes, to)
+		}
+	})
+
+	client.Pause(context.Background())
+	client.Resume(context.Background())
+
+	expected := []core.State{core.StatePaused, core.StateStarting, core.StateRunning}
+	if !slices.Equal(changes, expected) {
+		t.Errorf("Expected state changes %v, got %v", expected, changes)
+	}
+	if client.State() != core.StateRunning {
+		t.Errorf("Expected running client, got %s", client.State())
+	}
+}
+
+// TestNewDegraded tests that a client starts in degraded mode when a backend cannot start
+func TestNewDegraded(t *testing.T) {
+	occupied, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer occupied.Close()
+
+	cfg := config.Config{
+		ApplicationName: "degraded",
+		ProfileTypes:    []core.ProfileType{core.ProfileGoroutines},
+		InitialState:    core.ProfilingEnabled,
+		OutputDir:       t.TempDir(),
+		MemoryLimitMB:   1024,
+		Backends: []config.BackendConfig{
+			{Type: core.PprofBackend},
+			{Type: core.HTTPBackend, Config: config.Config{ServerAddress: occupied.Addr().String()}},
+		},
+	}
+
+	if _, err := New(context.Background(), cfg); err == nil {
+		t.Fatal("New should fail when a backend cannot start")
+	}
+
+	cfg.AllowDegraded = true
+	client, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("New returned error in degraded mode: %v", err)
+	}
+	defer client.Stop(context.Background())
+
+	status := client.Status()
+	if !status.Degraded || status.Profilers["pprof"].State != core.StateRunning || status.Profilers["http"].State != core.StateFailed {
+		t.Errorf("Expected pprof running and http failed, got %+v", status)
+	}
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+
+	"github.com/wasilak/profilego/core"
+)
+
+// BackendConfig configures one of several backends run side by side
+type BackendConfig struct {
+	// Name identifies the backend, defaults to its type
+	Name string `json:"name"`
+
+	// Type specifies the profiling backend (pyroscope, pprof, http)
+	Type core.BackendType `json:"type"`
+
+	// Config overrides the non-zero fields of the parent configuration for this backend.
+	// In files these fields are given next to name and type.
+	Config Config `json:"-"`
+}
+
+// UnmarshalJSON decodes a backend entry whose configuration keys sit next to name and type
+func (b *BackendConfig) UnmarshalJSON(data []byte) error {
+	values := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	var errs []error
+	for key, target := range map[string]interface{}{"name": &b.Name, "type": &b.Type} {
+		if raw, ok := values[key]; ok {
+			if err := json.Unmarshal(raw, target); err != nil {
+				errs = append(errs, &ConfigError{Field: key, Message: "invalid value for " + key + ": " + err.Error()})
+			}
+			delete(values, key)
+		}
+	}
+
+	_, err := setFields(values, &b.Config, "backend "+b.name())
+	errs = append(errs, err)
+
+	return errors.Join(errs...)
+}
+
+// name returns the backend name, defaulting to its type
+func (b BackendConfig) name() string {
+	if b.Name != "" {
+		return b.Name
+	}
+	return string(b.Type)
+}
+
+// EffectiveBackends returns the backends to run, each with its name set and its configuration
+// fully merged over the parent. Without Backends, the top-level Backend is the only one.
+// The Go runtime runs a single CPU profile per process, so only the backend returned by
+// CPUBackends keeps ProfileCPU; it is removed from the profile types of the others.
+func (c Config) EffectiveBackends() []BackendConfig {
+	backends := c.mergedBackends()
+	_, others := cpuBackends(backends)
+	for _, i := range others {
+		cfg := &backends[i].Config
+		cfg.ProfileTypes = slices.DeleteFunc(slices.Clone(cfg.profileTypes()), func(pt core.ProfileType) bool {
+			return pt == core.ProfileCPU
+		})
+	}
+	return backends
+}
+
+// CPUBackends returns the name of the backend collecting the CPU profile, empty if none does,
+// and the names of the other backends configured to collect it. pprof backends win over
+// Pyroscope, which wins over the on-demand HTTP endpoint; among backends of the same type the
+// first one in Backends does.
+func (c Config) CPUBackends() (string, []string) {
+	backends := c.mergedBackends()
+	owner, others := cpuBackends(backends)
+	if owner < 0 {
+		return "", nil
+	}
+	names := make([]string, len(others))
+	for i, j := range others {
+		names[i] = backends[j].Name
+	}
+	return backends[owner].Name, names
+}
+
+// cpuPriority orders the backend types when picking the one collecting the CPU profile
+var cpuPriority = []core.BackendType{core.PprofBackend, core.PyroscopeBackend, core.HTTPBackend}
+
+// cpuBackends returns the index of the backend collecting the CPU profile, -1 if none does,
+// and the indexes of the other backends configured to collect it
+func cpuBackends(backends []BackendConfig) (int, []int) {
+	var collecting []int
+	owner := -1
+	for i, b := range backends {
+		if !slices.Contains(b.Config.profileTypes(), core.ProfileCPU) {
+			continue
+		}
+		collecting = append(collecting, i)
+		if owner < 0 || slices.Index(cpuPriority, b.Type) < slices.Index(cpuPriority, backends[owner].Type) {
+			owner = i
+		}
+	}
+	return owner, slices.DeleteFunc(collecting, func(i int) bool { return i == owner })
+}
+
+// profileTypes returns the configured profile types, the ones of DefaultConfig if none are set
+func (c Config) profileTypes() []core.ProfileType {
+	if len(c.ProfileTypes) == 0 {
+		return DefaultConfig.ProfileTypes
+	}
+	return c.ProfileTypes
+}
+
+// mergedBackends returns the backends with their configuration merged over the parent
+func (c Config) mergedBackends() []BackendConfig {
+	if len(c.Backends) == 0 {
+		cfg := c.clone()
+		return []BackendConfig{{Name: string(c.Backend), Type: c.Backend, Config: cfg}}
+	}
+
+	backends := make([]BackendConfig, len(c.Backends))
+	for i, b := range c.Backends {
+		cfg := c.clone()
+		cfg.Backends = nil
+		applyOverrides(&cfg, b.Config.clone())
+		cfg.Backend = b.Type
+
+		backends[i] = BackendConfig{Name: b.name(), Type: b.Type, Config: cfg}
+	}
+	return backends
+}
+
+// validateBackends validates every backend with its merged configuration, prefixing the
+// reported fields with the backend's position
+func (c Config) validateBackends(v *ValidationError) {
+	backends := c.mergedBackends()
+	owner, others := cpuBackends(backends)
+
+	seen := make(map[string]bool)
+	for i, b := range backends {
+		prefix := "Backends[" + strconv.Itoa(i) + "]."
+
+		if len(c.Backends[i].Config.Backends) > 0 {
+			v.add(prefix+"Backends", "backends cannot be nested")
+		}
+
+		if seen[b.Name] {
+			v.add(prefix+"Name", "duplicate backend name "+strconv.Quote(b.Name)+", set a unique name")
+		}
+		seen[b.Name] = true
+
+		if slices.Contains(others, i) && len(b.Config.profileTypes()) == 1 {
+			v.add(prefix+"ProfileTypes", "the CPU profile is collected by backend "+strconv.Quote(backends[owner].Name)+
+				" since the Go runtime runs one CPU profile per process, set other profile types")
+		}
+
+		var verr *ValidationError
+		if errors.As(b.Config.Validate(), &verr) {
+			for _, e := range verr.Errors {
+				v.add(prefix+e.Field, e.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/wasilak/profilego/core"
+)
+
+func TestEffectiveBackends(t *testing.T) {
+	cfg := Config{
+		ApplicationName: "test-app",
+		Backend:         core.PyroscopeBackend,
+		ServerAddress:   "pyroscope:4040",
+		Tags:            map[string]string{"env": "prod"},
+		Backends: []BackendConfig{
+			{Type: core.PyroscopeBackend},
+			{Type: core.PprofBackend, Config: Config{OutputDir: "/var/lib/profiles"}},
+			{Name: "debug", Type: core.HTTPBackend, Config: Config{ServerAddress: "localhost:6060"}},
+		},
+	}
+
+	backends := cfg.EffectiveBackends()
+	if len(backends) != 3 {
+		t.Fatalf("Expected 3 backends, got %d", len(backends))
+	}
+
+	expected := []struct {
+		name    string
+		backend core.BackendType
+		address string
+	}{
+		{"pyroscope", core.PyroscopeBackend, "pyroscope:4040"},
+		{"pprof", core.PprofBackend, "pyroscope:4040"},
+		{"debug", core.HTTPBackend, "localhost:6060"},
+	}
+	for i, want := range expected {
+		b := backends[i]
+		if b.Name != want.name || b.Config.Backend != want.backend || b.Config.ServerAddress != want.address {
+			t.Errorf("Backend %d: expected %s/%s/%s, got %s/%s/%s", i,
+				want.name, want.backend, want.address, b.Name, b.Config.Backend, b.Config.ServerAddress)
+		}
+		if b.Config.ApplicationName != "test-app" || b.Config.Tags["env"] != "prod" {
+			t.Errorf("Backend %d should inherit the parent configuration, got %+v", i, b.Config)
+		}
+		if len(b.Config.Backends) != 0 {
+			t.Errorf("Backend %d should not carry nested backends", i)
+		}
+	}
+	if backends[1].Config.OutputDir != "/var/lib/profiles" {
+		t.Errorf("Expected pprof OutputDir override, got '%s'", backends[1].Config.OutputDir)
+	}
+
+	// Merging must not leak into the parent configuration
+	backends[0].Config.Tags["env"] = "changed"
+	if cfg.Tags["env"] != "prod" {
+		t.Error("EffectiveBackends should not share maps with the parent configuration")
+	}
+
+	single := Config{Backend: core.PprofBackend}.EffectiveBackends()
+	if len(single) != 1 || single[0].Name != "pprof" || single[0].Config.Backend != core.PprofBackend {
+		t.Errorf("Expected the top-level backend without Backends, got %+v", single)
+	}
+}
+
+func TestLoadFileBackends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profilego.yaml")
+	data := `application_name: file-app
+server_address: pyroscope:4040
+backends:
+  - type: pyroscope
+    tags:
+      team: core
+  - type: pprof
+    output_dir: /var/lib/profiles
+    snapshot_interval: 30s
+  - name: debug
+    type: http
+    server_address: localhost:6060
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig.clone()
+	if err := LoadFile(path, &cfg); err != nil {
+		t.Fatalf("LoadFile returned error: %v", err)
+	}
+
+	if len(cfg.Backends) != 3 {
+		t.Fatalf("Expected 3 backends, got %d", len(cfg.Backends))
+	}
+	if cfg.Backends[0].Config.Tags["team"] != "core" {
+		t.Errorf("Expected pyroscope tags, got %v", cfg.Backends[0].Config.Tags)
+	}
+	if cfg.Backends[1].Config.OutputDir != "/var/lib/profiles" || cfg.Backends[1].Config.SnapshotInterval.String() != "30s" {
+		t.Errorf("Unexpected pprof configuration %+v", cfg.Backends[1].Config)
+	}
+	if cfg.Backends[2].Name != "debug" || cfg.Backends[2].Type != core.HTTPBackend {
+		t.Errorf("Unexpected http backend %+v", cfg.Backends[2])
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate returned error: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("backends:\n  - type: pprof\n    output: /tmp\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadFile(path, &cfg); err == nil {
+		t.Error("LoadFile should report unknown keys in backend entries")
+	}
+}
+
+func TestEffectiveBackendsCPUProfile(t *testing.T) {
+	cfg := Config{
+		ApplicationName: "test-app",
+		ProfileTypes:    []core.ProfileType{core.ProfileCPU, core.ProfileGoroutines},
+		Backends: []BackendConfig{
+			{Type: core.PyroscopeBackend},
+			{Name: "debug", Type: core.HTTPBackend},
+			{Type: core.PprofBackend},
+			{Name: "archive", Type: core.PprofBackend},
+			{Name: "heap", Type: core.PprofBackend, Config: Config{ProfileTypes: []core.ProfileType{core.ProfileInuseSpace}}},
+		},
+	}
+
+	owner, others := cfg.CPUBackends()
+	if owner != "pprof" || !slices.Equal(others, []string{"pyroscope", "debug", "archive"}) {
+		t.Errorf("Expected the first pprof backend to collect the CPU profile, got %q over %v", owner, others)
+	}
+
+	expected := map[string][]core.ProfileType{
+		"pyroscope": {core.ProfileGoroutines},
+		"debug":     {core.ProfileGoroutines},
+		"pprof":     {core.ProfileCPU, core.ProfileGoroutines},
+		"archive":   {core.ProfileGoroutines},
+		"heap":      {core.ProfileInuseSpace},
+	}
+	for _, b := range cfg.EffectiveBackends() {
+		if !slices.Equal(b.Config.ProfileTypes, expected[b.Name]) {
+			t.Errorf("Backend %s: expected profile types %v, got %v", b.Name, expected[b.Name], b.Config.ProfileTypes)
+		}
+	}
+	if !slices.Equal(cfg.ProfileTypes, []core.ProfileType{core.ProfileCPU, core.ProfileGoroutines}) {
+		t.Errorf("EffectiveBackends should not change the parent profile types, got %v", cfg.ProfileTypes)
+	}
+
+	// Without a pprof backend Pyroscope wins over the HTTP endpoint, also with the default profile types
+	cfg = Config{Backends: []BackendConfig{{Type: core.HTTPBackend}, {Type: core.PyroscopeBackend}}}
+	if owner, others := cfg.CPUBackends(); owner != "pyroscope" || !slices.Equal(others, []string{"http"}) {
+		t.Errorf("Expected Pyroscope to collect the CPU profile, got %q over %v", owner, others)
+	}
+	if types := cfg.EffectiveBackends()[0].Config.ProfileTypes; len(types) == 0 || slices.Contains(types, core.ProfileCPU) {
+		t.Errorf("Expected the default profile types without CPU, got %v", types)
+	}
+}
+
+func TestValidateBackends(t *testing.T) {
+	cfg := Config{
+		ApplicationName: "test-app",
+		Backends: []BackendConfig{
+			{Type: core.PyroscopeBackend},
+			{Type: core.PprofBackend},
+			{Type: core.PprofBackend},
+			{Name: "debug", Type: core.HTTPBackend, Config: Config{ServerAddress: "http://localhost:6060"}},
+			{Name: "nested", Type: core.PprofBackend, Config: Config{Backends: []BackendConfig{{Type: core.PprofBackend}}}},
+			{Name: "unknown", Type: "jaeger"},
+		},
+	}
+
+	fields := invalidFields(t, cfg.Validate())
+
+	for _, field := range []string{
+		"Backends[0].ServerAddress",
+		"Backends[2].Name",
+		"Backends[3].ServerAddress",
+		"Backends[4].Backends",
+		"Backends[5].Backend",
+	} {
+		if fields[field] != 1 {
+			t.Errorf("Expected error for %s, got %v", field, fields)
+		}
+	}
+	if fields["Backends[1].Name"] != 0 {
+		t.Errorf("First backend of a type should not be reported as duplicate, got %v", fields)
+	}
+}
+
+func TestValidateBackendsCPUOnly(t *testing.T) {
+	cfg := Config{
+		ApplicationName: "test-app",
+		ServerAddress:   "localhost:4040",
+		ProfileTypes:    []core.ProfileType{core.ProfileCPU},
+		Backends: []BackendConfig{
+			{Type: core.PyroscopeBackend},
+			{Type: core.PprofBackend},
+		},
+	}
+
+	fields := invalidFields(t, cfg.Validate())
+	if fields["Backends[0].ProfileTypes"] != 1 || fields["Backends[1].ProfileTypes"] != 0 {
+		t.Errorf("Expected an error for the backend left without profile types, got %v", fields)
+	}
+}
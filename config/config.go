@@ -1,8 +1,12 @@
 package config
 
 import (
-	"github.com/wasilak/profilego/core"
+	"maps"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/wasilak/profilego/core"
 )
 
 // Config holds the configuration for profiling
@@ -10,7 +14,8 @@ type Config struct {
 	// ApplicationName specifies the name of the application being profiled
 	ApplicationName string `json:"application_name" env:"PROFILEGO_APP_NAME"`
 
-	// Backend specifies the profiling backend to use (pyroscope, pprof)
+	// Backend specifies the profiling backend to use (pyroscope, pprof, http).
+	// The http backend serves net/http/pprof and listens on ServerAddress.
 	Backend core.BackendType `json:"backend" env:"PROFILEGO_BACKEND"`
 
 	// ServerAddress specifies the address of the profiling server (for backends that require it)
@@ -22,19 +27,67 @@ type Config struct {
 	// ProfileTypes specifies which profile types to collect
 	ProfileTypes []core.ProfileType `json:"profile_types" env:"PROFILEGO_PROFILE_TYPES"`
 
+	// CPUProfileRate specifies how often the CPU profile samples, in Hz (defaults to 100).
+	// The Pyroscope backend always samples at 100 Hz. The Go runtime prints a warning to stderr
+	// whenever a CPU profile starts at another rate, so it only applies to the first CPU profile
+	// of the process; later pprof windows and HTTP requests sample at 100 Hz.
+	CPUProfileRate int `json:"cpu_profile_rate" env:"PROFILEGO_CPU_PROFILE_RATE"`
+
+	// MemProfileRate specifies the average number of bytes allocated between sampled allocations
+	// (0 keeps runtime.MemProfileRate, 512 KiB unless the application changed it).
+	// The rate is changed when profiling starts rather than at program startup, so heap profiles
+	// mix allocations sampled before and after and their totals are scaled at the new rate.
+	MemProfileRate int `json:"mem_profile_rate" env:"PROFILEGO_MEM_PROFILE_RATE"`
+
+	// MutexProfileFraction specifies that on average 1 in MutexProfileFraction mutex contention
+	// events is sampled (defaults to 10)
+	MutexProfileFraction int `json:"mutex_profile_fraction" env:"PROFILEGO_MUTEX_PROFILE_FRACTION"`
+
+	// BlockProfileRate specifies that on average one blocking event is sampled per BlockProfileRate
+	// spent blocked (defaults to 10µs). The runtime cannot report the block profile rate, so a rate
+	// the application set with runtime.SetBlockProfileRate is reset to 0 when profiling stops.
+	BlockProfileRate time.Duration `json:"block_profile_rate" env:"PROFILEGO_BLOCK_PROFILE_RATE"`
+
 	// InitialState specifies whether profiling starts enabled or disabled
 	InitialState core.ProfilingState `json:"initial_state" env:"PROFILEGO_INITIAL_STATE"`
 
 	// MemoryLimitMB specifies the maximum memory usage in MB
 	MemoryLimitMB int64 `json:"memory_limit_mb" env:"PROFILEGO_MEMORY_LIMIT_MB"`
 
+	// MemoryLimitPercent specifies the memory limit as a percentage of the container memory limit
+	// read from the cgroup; it takes precedence over MemoryLimitMB when the container has a limit
+	MemoryLimitPercent float64 `json:"memory_limit_percent" env:"PROFILEGO_MEMORY_LIMIT_PERCENT"`
+
+	// MemorySource specifies how memory usage is measured: runtime (memory mapped by the Go runtime,
+	// the default), heap_live (live heap), rss_estimate (mapped minus released memory),
+	// cgroup (container usage) or rss (resident set size of the process)
+	MemorySource core.MemorySource `json:"memory_source" env:"PROFILEGO_MEMORY_SOURCE"`
+
+	// CgroupRoot specifies where the cgroup filesystem is mounted (defaults to /sys/fs/cgroup)
+	CgroupRoot string `json:"cgroup_root" env:"PROFILEGO_CGROUP_ROOT"`
+
+	// MemoryCheckInterval specifies how often memory usage is checked against MemoryLimitMB while profiling
+	MemoryCheckInterval time.Duration `json:"memory_check_interval" env:"PROFILEGO_MEMORY_CHECK_INTERVAL"`
+
+	// MemoryResumeRatio specifies the fraction of MemoryLimitMB usage has to drop below before
+	// profile types suspended under memory pressure are resumed (defaults to 0.9)
+	MemoryResumeRatio float64 `json:"memory_resume_ratio" env:"PROFILEGO_MEMORY_RESUME_RATIO"`
+
+	// OverheadBudgetPercent specifies the estimated CPU time profiling may take, as a percentage
+	// of one CPU; above it sampling is reduced and expensive profile types are suspended (0 disables)
+	OverheadBudgetPercent float64 `json:"overhead_budget_percent" env:"PROFILEGO_OVERHEAD_BUDGET_PERCENT"`
+
+	// OverheadCheckInterval specifies how often the profiling overhead is measured
+	OverheadCheckInterval time.Duration `json:"overhead_check_interval" env:"PROFILEGO_OVERHEAD_CHECK_INTERVAL"`
+
 	// LogLevel specifies the log level for profiler logs
 	LogLevel string `json:"log_level" env:"PROFILEGO_LOG_LEVEL"`
 
-	// Timeout specifies timeout values for profiler operations
+	// Timeout specifies timeout values for profiler operations, DefaultConfig.Timeout if not set
 	Timeout time.Duration `json:"timeout" env:"PROFILEGO_TIMEOUT"`
 
-	// EnableTLS specifies whether to use TLS for server communication
+	// EnableTLS specifies whether to use TLS for server communication.
+	// TLS is also used when any other TLS setting is given or ServerAddress is an https URL.
 	EnableTLS bool `json:"enable_tls" env:"PROFILEGO_ENABLE_TLS"`
 
 	// TLSCertPath specifies path to TLS certificate file
@@ -46,6 +99,77 @@ type Config struct {
 	// SkipTLSVerify specifies whether to skip TLS verification
 	SkipTLSVerify bool `json:"skip_tls_verify" env:"PROFILEGO_SKIP_TLS_VERIFY"`
 
+	// TLSCAPath specifies path to a PEM bundle of CA certificates trusted for the server
+	TLSCAPath string `json:"tls_ca_path" env:"PROFILEGO_TLS_CA_PATH"`
+
+	// TLSMinVersion specifies the minimum TLS version (1.0, 1.1, 1.2 or 1.3, defaults to 1.2)
+	TLSMinVersion string `json:"tls_min_version" env:"PROFILEGO_TLS_MIN_VERSION"`
+
+	// BasicAuthUser specifies the user for HTTP basic authentication with the server
+	BasicAuthUser string `json:"basic_auth_user" env:"PROFILEGO_BASIC_AUTH_USER"`
+
+	// BasicAuthPassword specifies the password for HTTP basic authentication with the server
+	BasicAuthPassword string `json:"basic_auth_password" env:"PROFILEGO_BASIC_AUTH_PASSWORD"`
+
+	// BasicAuthPasswordFile specifies a file holding the basic authentication password, e.g. a mounted secret
+	BasicAuthPasswordFile string `json:"basic_auth_password_file" env:"PROFILEGO_BASIC_AUTH_PASSWORD_FILE"`
+
+	// BearerToken specifies a bearer/API token sent in the Authorization header
+	BearerToken string `json:"bearer_token" env:"PROFILEGO_BEARER_TOKEN"`
+
+	// BearerTokenFile specifies a file holding the bearer token; it is re-read when the file changes
+	BearerTokenFile string `json:"bearer_token_file" env:"PROFILEGO_BEARER_TOKEN_FILE"`
+
+	// TenantID specifies the tenant sent in the X-Scope-OrgID header for multi-tenant servers
+	TenantID string `json:"tenant_id" env:"PROFILEGO_TENANT_ID"`
+
+	// HTTPHeaders specifies additional headers sent with every upload
+	HTTPHeaders map[string]string `json:"http_headers" env:"PROFILEGO_HTTP_HEADERS"`
+
+	// SnapshotInterval specifies how often the pprof backend writes profile snapshots.
+	// CPU profiles are rotated into windows of the same length.
+	SnapshotInterval time.Duration `json:"snapshot_interval" env:"PROFILEGO_SNAPSHOT_INTERVAL"`
+
+	// OutputDir specifies the directory the pprof backend writes profiles to
+	OutputDir string `json:"output_dir" env:"PROFILEGO_OUTPUT_DIR"`
+
+	// FilenameTemplate specifies the name of profile files written by the pprof backend.
+	// Supported placeholders: {app}, {type}, {timestamp}, {hostname}, {pid}, {seq}
+	FilenameTemplate string `json:"filename_template" env:"PROFILEGO_FILENAME_TEMPLATE"`
+
+	// RetentionMaxFiles specifies how many profile files to keep per profile type (0 keeps all)
+	RetentionMaxFiles int `json:"retention_max_files" env:"PROFILEGO_RETENTION_MAX_FILES"`
+
+	// RetentionMaxAge specifies how long profile files are kept (0 keeps all)
+	RetentionMaxAge time.Duration `json:"retention_max_age" env:"PROFILEGO_RETENTION_MAX_AGE"`
+
+	// RetentionMaxBytes specifies the total size of profile files to keep (0 keeps all)
+	RetentionMaxBytes int64 `json:"retention_max_bytes" env:"PROFILEGO_RETENTION_MAX_BYTES"`
+
+	// RestartMaxAttempts specifies how often a profiler that failed to start is restarted
+	// before giving up (0 uses the default of 5, a negative value disables automatic restarts)
+	RestartMaxAttempts int `json:"restart_max_attempts" env:"PROFILEGO_RESTART_MAX_ATTEMPTS"`
+
+	// RestartBackoff specifies the delay before the first restart; it doubles with every attempt
+	RestartBackoff time.Duration `json:"restart_backoff" env:"PROFILEGO_RESTART_BACKOFF"`
+
+	// RestartMaxBackoff caps the delay between restarts
+	RestartMaxBackoff time.Duration `json:"restart_max_backoff" env:"PROFILEGO_RESTART_MAX_BACKOFF"`
+
+	// AllowDegraded lets initialization succeed when some profilers fail to start, e.g. because the
+	// server is unreachable at boot; they are left to be restarted in the background
+	AllowDegraded bool `json:"allow_degraded" env:"PROFILEGO_ALLOW_DEGRADED"`
+
+	// Backends specifies several backends to run side by side, e.g. Pyroscope push, local pprof
+	// files and an HTTP endpoint. When set, Backend is ignored and every other field acts as the
+	// default for each backend's own configuration. Lifecycle settings such as InitialState are
+	// taken from the parent configuration.
+	Backends []BackendConfig `json:"backends" env:"-"`
+
+	// TagPolicy limits the tags applied through AddTag and TagWrapper, the static Tags and global
+	// tags to keep the cardinality of profile series in check. It can only be set in code, as normalizers are functions.
+	TagPolicy *core.TagPolicy `json:"-" env:"-"`
+
 	// AdditionalAttrs specifies additional attributes for configuration merging
 	AdditionalAttrs []interface{} `json:"-" env:"-"`
 
@@ -73,25 +197,46 @@ var DefaultConfig = Config{
 		core.ProfileBlockCount,
 		core.ProfileBlockDuration,
 	},
-	InitialState:  core.ProfilingEnabled,
-	MemoryLimitMB: 50,
-	LogLevel:      "info",
-	Timeout:       10 * time.Second,
-	EnableTLS:     false,
-	SkipTLSVerify: false,
+	InitialState:        core.ProfilingEnabled,
+	MemoryLimitMB:       50,
+	MemoryCheckInterval: 5 * time.Second,
+	MemoryResumeRatio:   0.9,
+	LogLevel:            "info",
+	Timeout:             10 * time.Second,
+	EnableTLS:           false,
+	SkipTLSVerify:       false,
+	SnapshotInterval:    10 * time.Second,
+	FilenameTemplate:    "{app}_{type}_{timestamp}.pprof",
+
+	RestartMaxAttempts: 5,
+	RestartBackoff:     time.Second,
+	RestartMaxBackoff:  time.Minute,
+
+	OverheadCheckInterval: 10 * time.Second,
+
+	CPUProfileRate:       100,
+	MutexProfileFraction: 10,
+	BlockProfileRate:     10 * time.Microsecond,
 }
 
-// Validate validates the configuration parameters
-func (c Config) Validate() error {
-	if c.ApplicationName == "" {
-		return &ConfigError{Field: "ApplicationName", Message: "application name not provided"}
-	}
-
-	if c.ServerAddress == "" && c.Backend != core.PprofBackend {
-		return &ConfigError{Field: "ServerAddress", Message: "server address not provided for backend"}
+// clone returns a copy of the configuration that does not share maps or slices with c
+func (c Config) clone() Config {
+	c.Tags = maps.Clone(c.Tags)
+	c.HTTPHeaders = maps.Clone(c.HTTPHeaders)
+	c.ProfileTypes = slices.Clone(c.ProfileTypes)
+	c.AdditionalAttrs = slices.Clone(c.AdditionalAttrs)
+	c.Backends = slices.Clone(c.Backends)
+	for i := range c.Backends {
+		c.Backends[i].Config = c.Backends[i].Config.clone()
 	}
+	return c
+}
 
-	return nil
+// TLSEnabled returns whether uploads use TLS: when EnableTLS is set, when any other TLS setting
+// is given or when ServerAddress is an https URL
+func (c Config) TLSEnabled() bool {
+	return c.EnableTLS || c.SkipTLSVerify || c.TLSCertPath != "" || c.TLSKeyPath != "" || c.TLSCAPath != "" ||
+		c.TLSMinVersion != "" || strings.HasPrefix(strings.ToLower(c.ServerAddress), "https://")
 }
 
 // ConfigError represents an error in configuration
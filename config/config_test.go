@@ -112,3 +112,23 @@ func TestConfigError(t *testing.T) {
 		t.Errorf("Expected error '%s', got '%s'", expected, err.Error())
 	}
 }
+
+func TestTLSEnabled(t *testing.T) {
+	testCases := []struct {
+		cfg     Config
+		enabled bool
+	}{
+		{Config{ServerAddress: "localhost:4040"}, false},
+		{Config{ServerAddress: "http://localhost:4040"}, false},
+		{Config{ServerAddress: "localhost:4040", EnableTLS: true}, true},
+		{Config{ServerAddress: "https://localhost:4040"}, true},
+		{Config{ServerAddress: "localhost:4040", TLSCAPath: "/etc/ca.pem"}, true},
+		{Config{ServerAddress: "localhost:4040", TLSCertPath: "cert.pem", TLSKeyPath: "key.pem"}, true},
+	}
+
+	for _, tc := range testCases {
+		if enabled := tc.cfg.TLSEnabled(); enabled != tc.enabled {
+			t.Errorf("TLSEnabled() for %+v returned %v, want %v", tc.cfg, enabled, tc.enabled)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationType is used to tell time.Duration fields apart from plain integers
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// FromEnv returns DefaultConfig overridden by any PROFILEGO_* environment variables that are set
+func FromEnv() (Config, error) {
+	cfg := DefaultConfig.clone()
+	err := LoadEnv(&cfg)
+	return cfg, err
+}
+
+// LoadEnv overrides fields of cfg with the environment variables named by their env tags.
+// Fields whose variable is unset are left untouched. Every value that fails to parse is
+// reported as a *ConfigError, joined into the returned error; valid values are still applied.
+func LoadEnv(cfg *Config) error {
+	_, err := loadEnv(cfg)
+	return err
+}
+
+// loadEnv implements LoadEnv and additionally returns the names of the fields whose variable was set
+func loadEnv(cfg *Config) ([]string, error) {
+	var fields []string
+	var errs []error
+
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("env")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		if err := setFromString(v.Field(i), raw); err != nil {
+			errs = append(errs, &ConfigError{Field: field.Name, Message: "invalid value for " + name + ": " + err.Error()})
+			continue
+		}
+		fields = append(fields, field.Name)
+	}
+
+	return fields, errors.Join(errs...)
+}
+
+// setFromString parses raw into the given field according to its type
+func setFromString(field reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		if field.Type() == durationType {
+			d, err := time.ParseDuration(raw)
+			if err != nil {
+				return err
+			}
+			field.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported field type %s", field.Type())
+		}
+		items := splitList(raw)
+		slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+		for i, item := range items {
+			slice.Index(i).SetString(item)
+		}
+		field.Set(slice)
+	case reflect.Map:
+		if field.Type().Key().Kind() != reflect.String || field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported field type %s", field.Type())
+		}
+		m := reflect.MakeMap(field.Type())
+		for _, item := range splitList(raw) {
+			key, value, ok := strings.Cut(item, "=")
+			key = strings.TrimSpace(key)
+			if !ok || key == "" {
+				return fmt.Errorf("expected key=value pairs, got %q", item)
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(field.Type().Key()), reflect.ValueOf(strings.TrimSpace(value)).Convert(field.Type().Elem()))
+		}
+		field.Set(m)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
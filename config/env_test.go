@@ -0,0 +1,107 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wasilak/profilego/core"
+)
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("PROFILEGO_APP_NAME", "env-app")
+	t.Setenv("PROFILEGO_BACKEND", "pprof")
+	t.Setenv("PROFILEGO_TAGS", "env=prod, region = eu-west-1")
+	t.Setenv("PROFILEGO_PROFILE_TYPES", "cpu, inuse_space,,goroutines")
+	t.Setenv("PROFILEGO_MEMORY_LIMIT_MB", "128")
+	t.Setenv("PROFILEGO_TIMEOUT", "30s")
+	t.Setenv("PROFILEGO_ENABLE_TLS", "true")
+
+	cfg, err := FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv returned error: %v", err)
+	}
+
+	if cfg.ApplicationName != "env-app" {
+		t.Errorf("Expected ApplicationName 'env-app', got '%s'", cfg.ApplicationName)
+	}
+	if cfg.Backend != core.PprofBackend {
+		t.Errorf("Expected Backend pprof, got '%s'", cfg.Backend)
+	}
+	expectedTags := map[string]string{"env": "prod", "region": "eu-west-1"}
+	if !reflect.DeepEqual(cfg.Tags, expectedTags) {
+		t.Errorf("Expected Tags %v, got %v", expectedTags, cfg.Tags)
+	}
+	expectedTypes := []core.ProfileType{core.ProfileCPU, core.ProfileInuseSpace, core.ProfileGoroutines}
+	if !reflect.DeepEqual(cfg.ProfileTypes, expectedTypes) {
+		t.Errorf("Expected ProfileTypes %v, got %v", expectedTypes, cfg.ProfileTypes)
+	}
+	if cfg.MemoryLimitMB != 128 {
+		t.Errorf("Expected MemoryLimitMB 128, got %d", cfg.MemoryLimitMB)
+	}
+	if cfg.Timeout != 30*time.Second {
+		t.Errorf("Expected Timeout 30s, got %v", cfg.Timeout)
+	}
+	if !cfg.EnableTLS {
+		t.Error("Expected EnableTLS true")
+	}
+
+	// Unset variables keep their defaults
+	if cfg.ServerAddress != DefaultConfig.ServerAddress {
+		t.Errorf("Expected default ServerAddress '%s', got '%s'", DefaultConfig.ServerAddress, cfg.ServerAddress)
+	}
+	if cfg.LogLevel != DefaultConfig.LogLevel {
+		t.Errorf("Expected default LogLevel '%s', got '%s'", DefaultConfig.LogLevel, cfg.LogLevel)
+	}
+}
+
+func TestFromEnvDoesNotModifyDefaults(t *testing.T) {
+	cfg, err := FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv returned error: %v", err)
+	}
+
+	cfg.Tags["key"] = "value"
+	cfg.ProfileTypes[0] = core.ProfileGoroutines
+
+	if len(DefaultConfig.Tags) != 0 {
+		t.Errorf("DefaultConfig.Tags was modified: %v", DefaultConfig.Tags)
+	}
+	if DefaultConfig.ProfileTypes[0] != core.ProfileCPU {
+		t.Errorf("DefaultConfig.ProfileTypes was modified: %v", DefaultConfig.ProfileTypes)
+	}
+}
+
+func TestLoadEnvErrors(t *testing.T) {
+	t.Setenv("PROFILEGO_APP_NAME", "env-app")
+	t.Setenv("PROFILEGO_TIMEOUT", "ten seconds")
+	t.Setenv("PROFILEGO_SKIP_TLS_VERIFY", "maybe")
+	t.Setenv("PROFILEGO_TAGS", "env")
+
+	cfg := Config{}
+	err := LoadEnv(&cfg)
+	if err == nil {
+		t.Fatal("LoadEnv should return error for invalid values")
+	}
+
+	// Valid values are applied despite errors in other fields
+	if cfg.ApplicationName != "env-app" {
+		t.Errorf("Expected ApplicationName 'env-app', got '%s'", cfg.ApplicationName)
+	}
+
+	fields := map[string]bool{}
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var configErr *ConfigError
+		if !errors.As(e, &configErr) {
+			t.Fatalf("Expected *ConfigError, got %T", e)
+		}
+		fields[configErr.Field] = true
+	}
+
+	for _, field := range []string{"Timeout", "SkipTLSVerify", "Tags"} {
+		if !fields[field] {
+			t.Errorf("Expected error for field %s, got %v", field, err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFile overrides fields of cfg with the values found in a JSON or YAML file.
+// The format is chosen by extension (.json, .yaml or .yml) and keys use the json tag names.
+// Durations may be given as Go duration strings ("30s") or as nanoseconds.
+// Keys that are absent from the file leave the corresponding fields untouched.
+func LoadFile(path string, cfg *Config) error {
+	_, err := loadFile(path, cfg)
+	return err
+}
+
+// loadFile implements LoadFile and additionally returns the names of the fields set by the file
+func loadFile(path string, cfg *Config) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := decodeFile(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return setFields(values, cfg, path)
+}
+
+// setFields sets the fields of cfg named by the json tags in values and returns their names.
+// Keys that do not match a field are reported as errors mentioning source.
+func setFields(values map[string]json.RawMessage, cfg *Config, source string) ([]string, error) {
+	var fields []string
+	var errs []error
+
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	known := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		known[key] = true
+
+		raw, ok := values[key]
+		if !ok {
+			continue
+		}
+
+		if err := setFromJSON(v.Field(i), raw); err != nil {
+			errs = append(errs, &ConfigError{Field: field.Name, Message: "invalid value for " + key + ": " + err.Error()})
+			continue
+		}
+		fields = append(fields, field.Name)
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if !known[key] {
+			errs = append(errs, &ConfigError{Field: key, Message: "unknown configuration key in " + source})
+		}
+	}
+
+	return fields, errors.Join(errs...)
+}
+
+// decodeFile decodes the top-level keys of a JSON or YAML document
+func decodeFile(path string, data []byte) (map[string]json.RawMessage, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+	case ".yaml", ".yml":
+		// YAML is converted to JSON so both formats share the json tags and decoding rules
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, &ConfigError{Field: "file", Message: "failed to parse " + path + ": " + err.Error()}
+		}
+		converted, err := json.Marshal(doc)
+		if err != nil {
+			return nil, &ConfigError{Field: "file", Message: "failed to convert " + path + ": " + err.Error()}
+		}
+		data = converted
+	default:
+		return nil, &ConfigError{Field: "file", Message: "unsupported configuration file format: " + path}
+	}
+
+	values := make(map[string]json.RawMessage)
+	if len(bytes.TrimSpace(data)) == 0 || string(bytes.TrimSpace(data)) == "null" {
+		return values, nil
+	}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, &ConfigError{Field: "file", Message: "failed to parse " + path + ": " + err.Error()}
+	}
+	return values, nil
+}
+
+// setFromJSON decodes raw into the given field, accepting duration strings for time.Duration
+func setFromJSON(field reflect.Value, raw json.RawMessage) error {
+	if field.Type() == durationType {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return err
+			}
+			field.SetInt(int64(d))
+			return nil
+		}
+	}
+
+	value := reflect.New(field.Type())
+	if err := json.Unmarshal(raw, value.Interface()); err != nil {
+		return err
+	}
+	field.Set(value.Elem())
+	return nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wasilak/profilego/core"
+)
+
+func TestLoadFile(t *testing.T) {
+	for _, path := range []string{"testdata/profilego.yaml", "testdata/profilego.json"} {
+		t.Run(filepath.Ext(path), func(t *testing.T) {
+			cfg := DefaultConfig.clone()
+			if err := LoadFile(path, &cfg); err != nil {
+				t.Fatalf("LoadFile returned error: %v", err)
+			}
+
+			if cfg.ApplicationName != "file-app" {
+				t.Errorf("Expected ApplicationName 'file-app', got '%s'", cfg.ApplicationName)
+			}
+			if cfg.Backend != core.PprofBackend {
+				t.Errorf("Expected Backend pprof, got '%s'", cfg.Backend)
+			}
+			if !reflect.DeepEqual(cfg.Tags, map[string]string{"env": "staging"}) {
+				t.Errorf("Unexpected Tags %v", cfg.Tags)
+			}
+			if !reflect.DeepEqual(cfg.ProfileTypes, []core.ProfileType{core.ProfileCPU, core.ProfileGoroutines}) {
+				t.Errorf("Unexpected ProfileTypes %v", cfg.ProfileTypes)
+			}
+			if cfg.MemoryLimitMB != 100 {
+				t.Errorf("Expected MemoryLimitMB 100, got %d", cfg.MemoryLimitMB)
+			}
+			if cfg.Timeout != 5*time.Second {
+				t.Errorf("Expected Timeout 5s, got %v", cfg.Timeout)
+			}
+
+			// Keys absent from the file keep their previous values
+			if cfg.LogLevel != DefaultConfig.LogLevel {
+				t.Errorf("Expected default LogLevel '%s', got '%s'", DefaultConfig.LogLevel, cfg.LogLevel)
+			}
+		})
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	unknown := filepath.Join(dir, "unknown.yaml")
+	if err := os.WriteFile(unknown, []byte("application_name: app\napplication: typo\ntimeout: soon\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{}
+	err := LoadFile(unknown, &cfg)
+	if err == nil {
+		t.Fatal("LoadFile should return error for unknown keys and invalid values")
+	}
+
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Errorf("Expected *ConfigError, got %T", err)
+	}
+	if cfg.ApplicationName != "app" {
+		t.Errorf("Expected ApplicationName 'app', got '%s'", cfg.ApplicationName)
+	}
+
+	unsupported := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(unsupported, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadFile(unsupported, &cfg); err == nil {
+		t.Error("LoadFile should return error for unsupported formats")
+	}
+
+	if err := LoadFile(filepath.Join(dir, "missing.json"), &cfg); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected os.ErrNotExist for missing file, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Source identifies the configuration layer a value came from
+type Source string
+
+const (
+	SourceDefault  Source = "default"
+	SourceFile     Source = "file"
+	SourceEnv      Source = "env"
+	SourceOverride Source = "override"
+)
+
+// Provenance maps Config field names to the source that set them
+type Provenance map[string]Source
+
+// Load resolves the configuration from layers in increasing order of precedence:
+// DefaultConfig, the file at path (skipped if path is empty), PROFILEGO_* environment
+// variables and finally the non-zero fields of overrides. The returned Provenance
+// tells which layer set each field.
+func Load(path string, overrides Config) (Config, Provenance, error) {
+	cfg := DefaultConfig.clone()
+
+	provenance := make(Provenance)
+	t := reflect.TypeOf(cfg)
+	for i := 0; i < t.NumField(); i++ {
+		provenance[t.Field(i).Name] = SourceDefault
+	}
+
+	var errs []error
+
+	if path != "" {
+		fields, err := loadFile(path, &cfg)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		provenance.set(fields, SourceFile)
+	}
+
+	fields, err := loadEnv(&cfg)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	provenance.set(fields, SourceEnv)
+
+	provenance.set(applyOverrides(&cfg, overrides), SourceOverride)
+
+	return cfg, provenance, errors.Join(errs...)
+}
+
+// set records the source for the given fields
+func (p Provenance) set(fields []string, source Source) {
+	for _, field := range fields {
+		p[field] = source
+	}
+}
+
+// applyOverrides copies every non-zero field of overrides into cfg and returns their names
+func applyOverrides(cfg *Config, overrides Config) []string {
+	var fields []string
+
+	dst := reflect.ValueOf(cfg).Elem()
+	src := reflect.ValueOf(overrides)
+	for i := 0; i < src.NumField(); i++ {
+		if src.Field(i).IsZero() {
+			continue
+		}
+		dst.Field(i).Set(src.Field(i))
+		fields = append(fields, src.Type().Field(i).Name)
+	}
+
+	return fields
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/wasilak/profilego/core"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	t.Setenv("PROFILEGO_SERVER_ADDRESS", "env.internal:4040")
+	t.Setenv("PROFILEGO_MEMORY_LIMIT_MB", "200")
+
+	cfg, provenance, err := Load("testdata/profilego.yaml", Config{MemoryLimitMB: 300})
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	testCases := []struct {
+		field    string
+		source   Source
+		actual   interface{}
+		expected interface{}
+	}{
+		{"LogLevel", SourceDefault, cfg.LogLevel, DefaultConfig.LogLevel},
+		{"ApplicationName", SourceFile, cfg.ApplicationName, "file-app"},
+		{"Backend", SourceFile, cfg.Backend, core.PprofBackend},
+		{"ServerAddress", SourceEnv, cfg.ServerAddress, "env.internal:4040"},
+		{"MemoryLimitMB", SourceOverride, cfg.MemoryLimitMB, int64(300)},
+	}
+
+	for _, tc := range testCases {
+		if tc.actual != tc.expected {
+			t.Errorf("%s = %v, want %v", tc.field, tc.actual, tc.expected)
+		}
+		if provenance[tc.field] != tc.source {
+			t.Errorf("provenance[%s] = %s, want %s", tc.field, provenance[tc.field], tc.source)
+		}
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	cfg, provenance, err := Load("", Config{})
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	if cfg.ApplicationName != DefaultConfig.ApplicationName {
+		t.Errorf("Expected default ApplicationName, got '%s'", cfg.ApplicationName)
+	}
+	for field, source := range provenance {
+		if source != SourceDefault {
+			t.Errorf("provenance[%s] = %s, want %s", field, source, SourceDefault)
+		}
+	}
+}
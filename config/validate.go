@@ -0,0 +1,383 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/wasilak/profilego/core"
+)
+
+// reservedTagKeys are tag keys used internally by Pyroscope
+var reservedTagKeys = map[string]bool{
+	"__name__":       true,
+	"__session_id__": true,
+}
+
+// ValidationError collects every problem found while validating a configuration.
+// Each problem is a *ConfigError reachable through errors.As.
+type ValidationError struct {
+	Errors []*ConfigError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the individual configuration errors
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// add records a problem with the given field
+func (e *ValidationError) add(field, message string) {
+	e.Errors = append(e.Errors, &ConfigError{Field: field, Message: message})
+}
+
+// err returns v if any problem was recorded and nil otherwise
+func (e *ValidationError) err() error {
+	if len(e.Errors) > 0 {
+		return e
+	}
+	return nil
+}
+
+// Validate validates the configuration parameters.
+// All problems are reported at once as a *ValidationError.
+func (c Config) Validate() error {
+	v := &ValidationError{}
+
+	// Every backend is validated with the parent configuration merged in
+	if len(c.Backends) > 0 {
+		c.validateBackends(v)
+		return v.err()
+	}
+
+	if c.ApplicationName == "" {
+		v.add("ApplicationName", "application name not provided")
+	} else if c.Backend == core.PyroscopeBackend {
+		for _, r := range c.ApplicationName {
+			if !isTagKeyRune(r) && r != '-' && r != '.' && r != '/' {
+				v.add("ApplicationName", "application name contains invalid character "+strconv.QuoteRune(r))
+				break
+			}
+		}
+	}
+
+	if !c.Backend.IsValid() {
+		v.add("Backend", "unsupported backend type: "+strconv.Quote(string(c.Backend)))
+	}
+
+	if c.ServerAddress == "" {
+		if c.Backend != core.PprofBackend {
+			v.add("ServerAddress", "server address not provided for backend")
+		}
+	} else if c.Backend == core.HTTPBackend && !isHostPort(c.ServerAddress) {
+		v.add("ServerAddress", "http backend listen address must be host:port, got "+strconv.Quote(c.ServerAddress))
+	} else if msg := validateServerAddress(c.ServerAddress); msg != "" {
+		v.add("ServerAddress", msg)
+	}
+
+	for _, pt := range c.ProfileTypes {
+		if !pt.IsValid() {
+			v.add("ProfileTypes", "unknown profile type: "+strconv.Quote(string(pt)))
+		}
+	}
+
+	if c.InitialState != "" && !c.InitialState.IsValid() {
+		v.add("InitialState", "invalid initial state: "+strconv.Quote(string(c.InitialState)))
+	}
+
+	if c.MemoryLimitMB < 0 {
+		v.add("MemoryLimitMB", "memory limit must not be negative")
+	}
+
+	if c.MemoryLimitPercent < 0 || c.MemoryLimitPercent > 100 {
+		v.add("MemoryLimitPercent", "memory limit percentage must be between 0 and 100")
+	}
+
+	if c.MemorySource != "" && !c.MemorySource.IsValid() {
+		v.add("MemorySource", "invalid memory source: "+strconv.Quote(string(c.MemorySource)))
+	}
+
+	if c.MemoryCheckInterval < 0 {
+		v.add("MemoryCheckInterval", "memory check interval must not be negative")
+	}
+
+	if c.MemoryResumeRatio < 0 || c.MemoryResumeRatio > 1 {
+		v.add("MemoryResumeRatio", "memory resume ratio must be between 0 and 1")
+	}
+
+	if c.CPUProfileRate < 0 {
+		v.add("CPUProfileRate", "CPU profile rate must not be negative")
+	}
+
+	if c.MemProfileRate < 0 {
+		v.add("MemProfileRate", "memory profile rate must not be negative")
+	}
+
+	if c.MutexProfileFraction < 0 {
+		v.add("MutexProfileFraction", "mutex profile fraction must not be negative")
+	}
+
+	if c.BlockProfileRate < 0 {
+		v.add("BlockProfileRate", "block profile rate must not be negative")
+	}
+
+	if c.OverheadBudgetPercent < 0 || c.OverheadBudgetPercent > 100 {
+		v.add("OverheadBudgetPercent", "overhead budget must be between 0 and 100")
+	}
+
+	if c.OverheadCheckInterval < 0 {
+		v.add("OverheadCheckInterval", "overhead check interval must not be negative")
+	}
+
+	if c.LogLevel != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+			v.add("LogLevel", "invalid log level: "+strconv.Quote(c.LogLevel))
+		}
+	}
+
+	if c.Timeout < 0 {
+		v.add("Timeout", "timeout must not be negative")
+	}
+
+	c.validateTLS(v)
+	c.validateAuth(v)
+	c.validateTagPolicy(v)
+
+	if c.Backend == core.PyroscopeBackend {
+		for _, key := range slices.Sorted(maps.Keys(c.Tags)) {
+			if msg := validateTagKey(key); msg != "" {
+				v.add("Tags", msg)
+			}
+			if msg := validateTagValue(c.Tags[key]); msg != "" {
+				v.add("Tags", msg)
+			}
+		}
+	}
+
+	if c.SnapshotInterval < 0 {
+		v.add("SnapshotInterval", "snapshot interval must not be negative")
+	}
+
+	if c.FilenameTemplate != "" && !strings.Contains(c.FilenameTemplate, "{type}") {
+		v.add("FilenameTemplate", "filename template must contain the {type} placeholder")
+	}
+
+	if c.RetentionMaxFiles < 0 {
+		v.add("RetentionMaxFiles", "retention file count must not be negative")
+	}
+
+	if c.RetentionMaxAge < 0 {
+		v.add("RetentionMaxAge", "retention age must not be negative")
+	}
+
+	if c.RetentionMaxBytes < 0 {
+		v.add("RetentionMaxBytes", "retention size must not be negative")
+	}
+
+	if c.RestartBackoff < 0 {
+		v.add("RestartBackoff", "restart backoff must not be negative")
+	}
+
+	if c.RestartMaxBackoff < 0 {
+		v.add("RestartMaxBackoff", "maximum restart backoff must not be negative")
+	} else if c.RestartMaxBackoff > 0 && c.RestartMaxBackoff < c.RestartBackoff {
+		v.add("RestartMaxBackoff", "maximum restart backoff must not be shorter than the restart backoff")
+	}
+
+	return v.err()
+}
+
+// validateTLS checks that the certificate and key files exist and form a key pair
+func (c Config) validateTLS(v *ValidationError) {
+	if (c.TLSCertPath == "") != (c.TLSKeyPath == "") {
+		v.add("TLSCertPath", "TLSCertPath and TLSKeyPath must be set together")
+	}
+
+	certExists := fileExists(v, "TLSCertPath", c.TLSCertPath)
+	keyExists := fileExists(v, "TLSKeyPath", c.TLSKeyPath)
+
+	if certExists && keyExists {
+		if _, err := tls.LoadX509KeyPair(c.TLSCertPath, c.TLSKeyPath); err != nil {
+			v.add("TLSCertPath", "certificate and key do not form a valid pair: "+err.Error())
+		}
+	}
+
+	fileExists(v, "TLSCAPath", c.TLSCAPath)
+
+	if _, err := TLSVersion(c.TLSMinVersion); err != nil {
+		v.add("TLSMinVersion", err.Error())
+	}
+}
+
+// validateAuth checks that the credentials are complete and do not conflict
+func (c Config) validateAuth(v *ValidationError) {
+	if c.BasicAuthPassword != "" && c.BasicAuthPasswordFile != "" {
+		v.add("BasicAuthPassword", "BasicAuthPassword and BasicAuthPasswordFile are mutually exclusive")
+	}
+	hasPassword := c.BasicAuthPassword != "" || c.BasicAuthPasswordFile != ""
+	if (c.BasicAuthUser != "") != hasPassword {
+		v.add("BasicAuthUser", "BasicAuthUser and a basic authentication password must be set together")
+	}
+
+	if c.BearerToken != "" && c.BearerTokenFile != "" {
+		v.add("BearerToken", "BearerToken and BearerTokenFile are mutually exclusive")
+	}
+	if c.BasicAuthUser != "" && (c.BearerToken != "" || c.BearerTokenFile != "") {
+		v.add("BearerToken", "basic authentication and bearer token are mutually exclusive")
+	}
+
+	fileExists(v, "BasicAuthPasswordFile", c.BasicAuthPasswordFile)
+	fileExists(v, "BearerTokenFile", c.BearerTokenFile)
+
+	for _, key := range slices.Sorted(maps.Keys(c.HTTPHeaders)) {
+		if key == "" || strings.ContainsAny(key, " \t\r\n:") {
+			v.add("HTTPHeaders", "invalid header name: "+strconv.Quote(key))
+		}
+	}
+}
+
+// TLSVersion converts a version such as "1.2" to its crypto/tls constant.
+// An empty version defaults to TLS 1.2.
+func TLSVersion(version string) (uint16, error) {
+	switch version {
+	case "":
+		return tls.VersionTLS12, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version: %q", version)
+	}
+}
+
+// fileExists reports whether path is set and accessible, recording a problem if it is set but not accessible
+func fileExists(v *ValidationError, field, path string) bool {
+	if path == "" {
+		return false
+	}
+	if _, err := os.Stat(path); err != nil {
+		v.add(field, "cannot access "+path+": "+err.Error())
+		return false
+	}
+	return true
+}
+
+// validateServerAddress checks that addr is either an http(s) URL, a host:port pair or a bare host,
+// which the Pyroscope backend sends to the default port of the scheme it adds
+func validateServerAddress(addr string) string {
+	if strings.Contains(addr, "://") {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return "invalid server address: " + err.Error()
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return "unsupported server address scheme: " + strconv.Quote(u.Scheme)
+		}
+		if u.Host == "" {
+			return "server address has no host: " + strconv.Quote(addr)
+		}
+		return ""
+	}
+
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		if u, parseErr := url.Parse("http://" + addr); parseErr == nil && u.Host == addr && u.Port() == "" {
+			return ""
+		}
+		return "invalid server address: " + err.Error()
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "invalid server address port: " + strconv.Quote(port)
+	}
+	return ""
+}
+
+// isHostPort returns whether addr is a host:port pair rather than a URL or a bare host
+func isHostPort(addr string) bool {
+	_, _, err := net.SplitHostPort(addr)
+	return err == nil && !strings.Contains(addr, "://")
+}
+
+// validateTagPolicy checks the limits of the tag policy, if any
+func (c Config) validateTagPolicy(v *ValidationError) {
+	if c.TagPolicy == nil {
+		return
+	}
+	if c.TagPolicy.MaxValuesPerKey < 0 {
+		v.add("TagPolicy", "max values per key must not be negative")
+	}
+	for _, key := range slices.Sorted(maps.Keys(c.TagPolicy.MaxValues)) {
+		if c.TagPolicy.MaxValues[key] < 0 {
+			v.add("TagPolicy", "max values of tag key "+strconv.Quote(key)+" must not be negative")
+		}
+	}
+}
+
+// ValidateTagKey checks a tag key set at runtime against the Pyroscope naming rules
+func ValidateTagKey(key string) error {
+	if msg := validateTagKey(key); msg != "" {
+		return &ConfigError{Field: "Tags", Message: msg}
+	}
+	return nil
+}
+
+// validateTagKey checks a tag key against the Pyroscope naming rules
+func validateTagKey(key string) string {
+	if key == "" {
+		return "tag key must not be empty"
+	}
+	for _, r := range key {
+		if !isTagKeyRune(r) {
+			return "tag key " + strconv.Quote(key) + " contains invalid character " + strconv.QuoteRune(r)
+		}
+	}
+	if reservedTagKeys[key] {
+		return "tag key " + strconv.Quote(key) + " is reserved"
+	}
+	return ""
+}
+
+// ValidateTagValue checks a tag value set at runtime for the characters delimiting tags in the application name
+func ValidateTagValue(value string) error {
+	if msg := validateTagValue(value); msg != "" {
+		return &ConfigError{Field: "Tags", Message: msg}
+	}
+	return nil
+}
+
+// validateTagValue checks a tag value for the characters delimiting tags in the application name
+func validateTagValue(value string) string {
+	if i := strings.IndexAny(value, ",={}"); i >= 0 {
+		return "tag value " + strconv.Quote(value) + " contains invalid character " + strconv.QuoteRune(rune(value[i]))
+	}
+	return ""
+}
+
+// isTagKeyRune returns whether r may be used in a Pyroscope tag key
+func isTagKeyRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_'
+}
@@ -0,0 +1,297 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wasilak/profilego/core"
+)
+
+// writeKeyPair writes a self-signed certificate and its key to dir
+func writeKeyPair(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
+
+// invalidFields returns the fields reported by a validation error
+func invalidFields(t *testing.T, err error) map[string]int {
+	t.Helper()
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected *ValidationError, got %T: %v", err, err)
+	}
+
+	fields := make(map[string]int)
+	for _, e := range validationErr.Errors {
+		fields[e.Field]++
+	}
+	return fields
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := Config{
+		ApplicationName:   "test-app",
+		Backend:           core.PyroscopeBackend,
+		ServerAddress:     "localhost:port",
+		ProfileTypes:      []core.ProfileType{core.ProfileCPU, "heap", "threads"},
+		InitialState:      "paused",
+		MemoryLimitMB:     -1,
+		MemoryResumeRatio: 1.5,
+		MemorySource:      "heap",
+		LogLevel:          "verbose",
+		Timeout:           -time.Second,
+		Tags:              map[string]string{"env": "prod", "http-method": "GET", "__name__": "x", "team": "a,b"},
+	}
+
+	fields := invalidFields(t, cfg.Validate())
+
+	expected := map[string]int{
+		"ServerAddress":     1,
+		"ProfileTypes":      2,
+		"InitialState":      1,
+		"MemoryLimitMB":     1,
+		"MemoryResumeRatio": 1,
+		"MemorySource":      1,
+		"LogLevel":          1,
+		"Timeout":           1,
+		"Tags":              3,
+	}
+	for field, count := range expected {
+		if fields[field] != count {
+			t.Errorf("Expected %d errors for %s, got %d", count, field, fields[field])
+		}
+	}
+	if len(fields) != len(expected) {
+		t.Errorf("Unexpected fields reported: %v", fields)
+	}
+}
+
+func TestValidateErrorsAreConfigErrors(t *testing.T) {
+	err := Config{Backend: "jaeger"}.Validate()
+
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("Expected errors.As to find *ConfigError in %v", err)
+	}
+
+	fields := invalidFields(t, err)
+	for _, field := range []string{"ApplicationName", "Backend", "ServerAddress"} {
+		if fields[field] != 1 {
+			t.Errorf("Expected error for %s, got %v", field, fields)
+		}
+	}
+}
+
+func TestValidateServerAddress(t *testing.T) {
+	testCases := []struct {
+		address string
+		valid   bool
+	}{
+		{"localhost:4040", true},
+		{"127.0.0.1:4040", true},
+		{"http://pyroscope:4040", true},
+		{"https://profiles.example.com", true},
+		{"localhost", true},
+		{"pyroscope.internal", true},
+		{"pyroscope.internal/ingest", false},
+		{"localhost:0", false},
+		{"ftp://pyroscope:4040", false},
+		{"http://", false},
+	}
+
+	for _, tc := range testCases {
+		cfg := Config{ApplicationName: "test-app", Backend: core.PyroscopeBackend, ServerAddress: tc.address}
+		err := cfg.Validate()
+		if (err == nil) != tc.valid {
+			t.Errorf("Validate() with ServerAddress %q returned %v, want valid=%v", tc.address, err, tc.valid)
+		}
+	}
+
+	// The http backend listens on ServerAddress, so it needs a port
+	cfg := Config{ApplicationName: "test-app", Backend: core.HTTPBackend, ServerAddress: "localhost"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should require a port for the http backend")
+	}
+}
+
+func TestValidateTLS(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeKeyPair(t, dir, "client")
+	otherCert, _ := writeKeyPair(t, dir, "other")
+
+	base := Config{ApplicationName: "test-app", Backend: core.PyroscopeBackend, ServerAddress: "localhost:4040", EnableTLS: true}
+
+	valid := base
+	valid.TLSCertPath, valid.TLSKeyPath = certPath, keyPath
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Valid key pair returned error: %v", err)
+	}
+
+	testCases := map[string]struct {
+		cert, key string
+	}{
+		"missing key":    {certPath, ""},
+		"missing cert":   {"", keyPath},
+		"nonexistent":    {filepath.Join(dir, "nope.crt"), keyPath},
+		"mismatched key": {otherCert, keyPath},
+	}
+	for name, tc := range testCases {
+		cfg := base
+		cfg.TLSCertPath, cfg.TLSKeyPath = tc.cert, tc.key
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: Validate should return error", name)
+		}
+	}
+}
+
+func TestValidateAuth(t *testing.T) {
+	// withAuth returns a valid Pyroscope config with the given authentication settings applied
+	withAuth := func(apply func(*Config)) Config {
+		cfg := Config{ApplicationName: "test-app", Backend: core.PyroscopeBackend, ServerAddress: "localhost:4040"}
+		apply(&cfg)
+		return cfg
+	}
+
+	valid := map[string]Config{
+		"basic auth":   withAuth(func(c *Config) { c.BasicAuthUser, c.BasicAuthPassword = "user", "password" }),
+		"bearer token": withAuth(func(c *Config) { c.BearerToken, c.TenantID = "token", "team-a" }),
+		"headers":      withAuth(func(c *Config) { c.HTTPHeaders = map[string]string{"X-Cluster": "eu-1"} }),
+	}
+	for name, cfg := range valid {
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("%s: Validate returned error: %v", name, err)
+		}
+	}
+
+	testCases := map[string]struct {
+		cfg   Config
+		field string
+	}{
+		"user without password": {withAuth(func(c *Config) { c.BasicAuthUser = "user" }), "BasicAuthUser"},
+		"password without user": {withAuth(func(c *Config) { c.BasicAuthPassword = "password" }), "BasicAuthUser"},
+		"password and file": {withAuth(func(c *Config) {
+			c.BasicAuthUser, c.BasicAuthPassword, c.BasicAuthPasswordFile = "user", "password", "/dev/null"
+		}), "BasicAuthPassword"},
+		"token and file": {withAuth(func(c *Config) { c.BearerToken, c.BearerTokenFile = "token", "/dev/null" }), "BearerToken"},
+		"basic auth and token": {withAuth(func(c *Config) {
+			c.BasicAuthUser, c.BasicAuthPassword, c.BearerToken = "user", "password", "token"
+		}), "BearerToken"},
+		"missing token file":  {withAuth(func(c *Config) { c.BearerTokenFile = "/nonexistent/token" }), "BearerTokenFile"},
+		"invalid header name": {withAuth(func(c *Config) { c.HTTPHeaders = map[string]string{"X Cluster": "eu-1"} }), "HTTPHeaders"},
+	}
+	for name, tc := range testCases {
+		if fields := invalidFields(t, tc.cfg.Validate()); fields[tc.field] == 0 {
+			t.Errorf("%s: expected error for %s, got %v", name, tc.field, fields)
+		}
+	}
+}
+
+func TestValidateRestart(t *testing.T) {
+	testCases := map[string]struct {
+		cfg   Config
+		field string
+	}{
+		"negative backoff":         {Config{RestartBackoff: -time.Second}, "RestartBackoff"},
+		"negative maximum backoff": {Config{RestartMaxBackoff: -time.Second}, "RestartMaxBackoff"},
+		"maximum below backoff":    {Config{RestartBackoff: time.Minute, RestartMaxBackoff: time.Second}, "RestartMaxBackoff"},
+	}
+	for name, tc := range testCases {
+		tc.cfg.ApplicationName, tc.cfg.Backend, tc.cfg.ServerAddress = "test-app", core.PyroscopeBackend, "localhost:4040"
+		if fields := invalidFields(t, tc.cfg.Validate()); fields[tc.field] == 0 {
+			t.Errorf("%s: expected error for %s, got %v", name, tc.field, fields)
+		}
+	}
+}
+
+func TestValidateSamplingRates(t *testing.T) {
+	testCases := map[string]struct {
+		cfg   Config
+		field string
+	}{
+		"negative CPU rate":       {Config{CPUProfileRate: -1}, "CPUProfileRate"},
+		"negative memory rate":    {Config{MemProfileRate: -1}, "MemProfileRate"},
+		"negative mutex fraction": {Config{MutexProfileFraction: -1}, "MutexProfileFraction"},
+		"negative block rate":     {Config{BlockProfileRate: -time.Microsecond}, "BlockProfileRate"},
+	}
+	for name, tc := range testCases {
+		tc.cfg.ApplicationName, tc.cfg.Backend, tc.cfg.ServerAddress = "test-app", core.PyroscopeBackend, "localhost:4040"
+		if fields := invalidFields(t, tc.cfg.Validate()); fields[tc.field] == 0 {
+			t.Errorf("%s: expected error for %s, got %v", name, tc.field, fields)
+		}
+	}
+}
+
+func TestValidateOverhead(t *testing.T) {
+	testCases := map[string]struct {
+		cfg   Config
+		field string
+	}{
+		"negative budget":         {Config{OverheadBudgetPercent: -1}, "OverheadBudgetPercent"},
+		"budget over 100 percent": {Config{OverheadBudgetPercent: 150}, "OverheadBudgetPercent"},
+		"negative interval":       {Config{OverheadCheckInterval: -time.Second}, "OverheadCheckInterval"},
+	}
+	for name, tc := range testCases {
+		tc.cfg.ApplicationName, tc.cfg.Backend, tc.cfg.ServerAddress = "test-app", core.PyroscopeBackend, "localhost:4040"
+		if fields := invalidFields(t, tc.cfg.Validate()); fields[tc.field] == 0 {
+			t.Errorf("%s: expected error for %s, got %v", name, tc.field, fields)
+		}
+	}
+}
+
+func TestValidateTagPolicy(t *testing.T) {
+	testCases := map[string]*core.TagPolicy{
+		"negative limit":         {MaxValuesPerKey: -1},
+		"negative limit for key": {MaxValues: map[string]int{"user_id": -1}},
+	}
+	for name, policy := range testCases {
+		cfg := Config{ApplicationName: "test-app", Backend: core.PyroscopeBackend, ServerAddress: "localhost:4040", TagPolicy: policy}
+		if fields := invalidFields(t, cfg.Validate()); fields["TagPolicy"] == 0 {
+			t.Errorf("%s: expected error for TagPolicy, got %v", name, fields)
+		}
+	}
+}
+
+func TestValidateDefaultConfig(t *testing.T) {
+	if err := DefaultConfig.Validate(); err != nil {
+		t.Errorf("DefaultConfig should be valid: %v", err)
+	}
+}
@@ -26,11 +26,62 @@ type Profiler interface {
 // Tagger defines the interface for adding tags to profiles
 // This abstracts away the specific backend implementation
 type Tagger interface {
-	// AddTag adds a key-value pair tag to the current profiling context
-	AddTag(key, value string) error
+	// AddTag adds a key-value pair tag to the labels of ctx and sets them on the calling goroutine,
+	// so the tag applies to its remaining work and the goroutines it starts.
+	// The returned context carries the tag, so later tags are added to it
+	AddTag(ctx context.Context, key, value string) (context.Context, error)
+
+	// AddTagScoped adds a tag like AddTag and returns a release function
+	// restoring the labels of ctx on the calling goroutine
+	AddTagScoped(ctx context.Context, key, value string) (context.Context, func(), error)
 
 	// TagWrapper executes a function with additional profiling tags
 	// The tags are only applied during the execution of the function
 	// The provided context is passed to the function
 	TagWrapper(ctx context.Context, key, value string, fn func(context.Context) error) error
+
+	// TagWrapperLabels executes a function with several additional profiling tags at once,
+	// merged with the tags already present in the context
+	TagWrapperLabels(ctx context.Context, tags Tags, fn func(context.Context) error) error
+}
+
+// ProfileTypeSuspender is implemented by profilers that can stop collecting individual
+// profile types at runtime, e.g. to shed overhead under memory pressure
+type ProfileTypeSuspender interface {
+	// SuspendProfileTypes stops collecting the given profile types until they are resumed
+	SuspendProfileTypes(ctx context.Context, types []ProfileType) error
+
+	// ResumeProfileTypes resumes collecting suspended profile types
+	ResumeProfileTypes(ctx context.Context, types []ProfileType) error
+}
+
+// OverheadReporter is implemented by profilers that account for the cost of profiling itself
+type OverheadReporter interface {
+	// Overhead returns the overhead accumulated since the profiler was created
+	Overhead() OverheadStats
+}
+
+// SamplingReducer is implemented by profilers that can lower their sampling rates at runtime,
+// e.g. to stay within an overhead budget
+type SamplingReducer interface {
+	// ReduceSampling switches between the reduced and the configured sampling rates
+	ReduceSampling(ctx context.Context, reduced bool) error
+}
+
+// StateReporter is implemented by profilers tracking their own lifecycle state. The manager follows
+// their state machine instead of keeping a copy, so changes the profiler makes by itself, e.g.
+// failing while restarting, are reported to listeners and the supervisor.
+type StateReporter interface {
+	// StateMachine returns the state machine tracking the profiler's lifecycle
+	StateMachine() *StateMachine
+}
+
+// GlobalTagger is implemented by profilers whose static tags can change at runtime,
+// e.g. to follow a feature flag set or a leader/follower role without restarting
+type GlobalTagger interface {
+	// SetGlobalTag adds or replaces a tag applied to all profiles
+	SetGlobalTag(key, value string) error
+
+	// RemoveGlobalTag removes a tag applied to all profiles
+	RemoveGlobalTag(key string) error
 }
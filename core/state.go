@@ -0,0 +1,139 @@
+package core
+
+import "sync"
+
+// State represents the lifecycle state of a profiler
+type State string
+
+const (
+	StateInitialized State = "initialized"
+	StateStarting    State = "starting"
+	StateRunning     State = "running"
+	StatePaused      State = "paused"
+	StateStopping    State = "stopping"
+	StateStopped     State = "stopped"
+	StateFailed      State = "failed"
+)
+
+// transitions lists the states reachable from each state.
+// StateFailed is reachable from every state and is not listed.
+var transitions = map[State][]State{
+	StateInitialized: {StateStarting, StateStopping},
+	StateStarting:    {StateRunning},
+	StateRunning:     {StatePaused, StateStopping},
+	StatePaused:      {StateStarting, StateRunning, StateStopping},
+	StateStopping:    {StateStopped},
+	StateStopped:     {StateStarting},
+	StateFailed:      {StateStarting, StateStopping},
+}
+
+// CanTransition returns whether moving from one state to another is allowed
+func CanTransition(from, to State) bool {
+	if to == StateFailed {
+		return true
+	}
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError is returned when a lifecycle operation is not allowed in the current state
+type TransitionError struct {
+	Name string
+	From State
+	To   State
+}
+
+func (e *TransitionError) Error() string {
+	return "profiler " + e.Name + ": illegal state transition from " + string(e.From) + " to " + string(e.To)
+}
+
+// TimeoutError is returned when a profiler does not complete an operation before its context is done,
+// e.g. when a final upload does not finish within the shutdown deadline
+type TimeoutError struct {
+	Name      string
+	Operation string
+	Err       error
+}
+
+func (e *TimeoutError) Error() string {
+	return "profiler " + e.Name + ": " + e.Operation + " timed out: " + e.Err.Error()
+}
+
+// Unwrap returns the context error, e.g. context.DeadlineExceeded
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// StateListener is called after a state change. err is set when the change is to StateFailed.
+type StateListener func(name string, from, to State, err error)
+
+// StateMachine tracks the lifecycle state of a named profiler and notifies listeners of changes
+type StateMachine struct {
+	mu        sync.RWMutex
+	name      string
+	state     State
+	listeners []StateListener
+}
+
+// NewStateMachine creates a state machine in StateInitialized
+func NewStateMachine(name string) *StateMachine {
+	return &StateMachine{name: name, state: StateInitialized}
+}
+
+// State returns the current state
+func (m *StateMachine) State() State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// Is returns whether the current state is one of the given states
+func (m *StateMachine) Is(states ...State) bool {
+	current := m.State()
+	for _, s := range states {
+		if current == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves to the given state, returning a *TransitionError if that is not allowed
+func (m *StateMachine) Transition(to State) error {
+	return m.transition(to, nil)
+}
+
+// Fail moves to StateFailed, reporting err to the listeners, and returns err
+func (m *StateMachine) Fail(err error) error {
+	m.transition(StateFailed, err)
+	return err
+}
+
+// OnStateChange registers a listener called synchronously after every state change
+func (m *StateMachine) OnStateChange(listener StateListener) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, listener)
+}
+
+// transition changes the state and notifies the listeners outside the lock
+func (m *StateMachine) transition(to State, err error) error {
+	m.mu.Lock()
+	from := m.state
+	if !CanTransition(from, to) {
+		m.mu.Unlock()
+		return &TransitionError{Name: m.name, From: from, To: to}
+	}
+	m.state = to
+	listeners := m.listeners
+	m.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(m.name, from, to, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	testCases := []struct {
+		from, to State
+		allowed  bool
+	}{
+		{StateInitialized, StateStarting, true},
+		{StateStarting, StateRunning, true},
+		{StateRunning, StatePaused, true},
+		{StatePaused, StateStarting, true},
+		{StateRunning, StateStopping, true},
+		{StateStopping, StateStopped, true},
+		{StateStopped, StateStarting, true},
+		{StateFailed, StateStarting, true},
+		{StateStarting, StateFailed, true},
+		{StateInitialized, StateRunning, false},
+		{StateStopped, StatePaused, false},
+		{StateStopped, StateRunning, false},
+		{StateRunning, StateStarting, false},
+		{StateStopping, StateRunning, false},
+	}
+
+	for _, tc := range testCases {
+		if got := CanTransition(tc.from, tc.to); got != tc.allowed {
+			t.Errorf("CanTransition(%s, %s) = %v, expected %v", tc.from, tc.to, got, tc.allowed)
+		}
+	}
+}
+
+func TestStateMachine(t *testing.T) {
+	type event struct {
+		from, to State
+		err      error
+	}
+	var events []event
+
+	m := NewStateMachine("test")
+	m.OnStateChange(func(name string, from, to State, err error) {
+		if name != "test" {
+			t.Errorf("Expected name 'test', got '%s'", name)
+		}
+		events = append(events, event{from, to, err})
+	})
+
+	if m.State() != StateInitialized {
+		t.Fatalf("Expected initial state, got %s", m.State())
+	}
+
+	if err := m.Transition(StateStarting); err != nil {
+		t.Fatalf("Transition returned error: %v", err)
+	}
+
+	err := m.Transition(StatePaused)
+	var transitionErr *TransitionError
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("Expected *TransitionError, got %v", err)
+	}
+	if transitionErr.From != StateStarting || transitionErr.To != StatePaused {
+		t.Errorf("Unexpected transition error %+v", transitionErr)
+	}
+	if m.State() != StateStarting {
+		t.Errorf("Illegal transition should not change the state, got %s", m.State())
+	}
+
+	failure := errors.New("boom")
+	if err := m.Fail(failure); err != failure {
+		t.Errorf("Fail should return its error, got %v", err)
+	}
+	if !m.Is(StateFailed) {
+		t.Errorf("Expected failed state, got %s", m.State())
+	}
+
+	expected := []event{{StateInitialized, StateStarting, nil}, {StateStarting, StateFailed, failure}}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %v", len(expected), events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("Event %d: expected %v, got %v", i, expected[i], events[i])
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultOverflowValue replaces tag values over the limit of their key when OverflowValue is not set
+const DefaultOverflowValue = "other"
+
+// TagNormalizer rewrites a tag value before it is applied, e.g. to template IDs out of paths
+type TagNormalizer func(value string) string
+
+// TagPolicy limits the tags applied through a Tagger to keep the cardinality of profile series in check.
+// Tags are checked against AllowKeys and DenyKeys, normalized, then limited per key.
+type TagPolicy struct {
+	// MaxValuesPerKey is the number of distinct values kept per tag key, 0 for no limit.
+	// Further values are replaced with OverflowValue.
+	MaxValuesPerKey int
+	// MaxValues overrides MaxValuesPerKey for individual keys
+	MaxValues map[string]int
+	// OverflowValue replaces values over the limit, DefaultOverflowValue if empty
+	OverflowValue string
+	// AllowKeys lists the only tag keys applied if set; tags with other keys are dropped
+	AllowKeys []string
+	// DenyKeys lists tag keys that are dropped
+	DenyKeys []string
+	// Normalizers rewrite the values of a tag key, applied in order
+	Normalizers map[string][]TagNormalizer
+}
+
+// Limit returns the number of distinct values kept for a key, 0 for no limit
+func (p TagPolicy) Limit(key string) int {
+	if limit, ok := p.MaxValues[key]; ok {
+		return limit
+	}
+	return p.MaxValuesPerKey
+}
+
+// TagPolicyStats counts the tags changed by a TagPolicy, per tag key
+type TagPolicyStats struct {
+	Dropped   map[string]int64 // tags dropped by AllowKeys or DenyKeys
+	Collapsed map[string]int64 // values over the limit replaced with the overflow value
+}
+
+// pathIDPattern matches path segments that identify a resource: numbers, UUIDs and long hex strings
+var pathIDPattern = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// NormalizePath replaces the path segments identifying a resource with ":id" and drops the query,
+// e.g. /users/123/orders?page=2 turns into /users/:id/orders
+func NormalizePath(value string) string {
+	path, _, _ := strings.Cut(value, "?")
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if pathIDPattern.MatchString(segment) {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Lowercase is a TagNormalizer converting values to lower case
+func Lowercase(value string) string {
+	return strings.ToLower(value)
+}
+
+// Truncate returns a TagNormalizer cutting values to at most max bytes, without splitting a character.
+// A max of 0 or less empties every value.
+func Truncate(max int) TagNormalizer {
+	return func(value string) string {
+		if max <= 0 {
+			return ""
+		}
+		if len(value) <= max {
+			return value
+		}
+		n := max
+		for n > 0 && !utf8.RuneStart(value[n]) {
+			n--
+		}
+		return value[:n]
+	}
+}
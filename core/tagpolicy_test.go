@@ -0,0 +1,59 @@
+package core
+
+import "testing"
+
+func TestNormalizePath(t *testing.T) {
+	tests := map[string]string{
+		"/users/123":                                  "/users/:id",
+		"/users/123/orders/456?page=2":                "/users/:id/orders/:id",
+		"/items/3f2504e0-4f89-11d3-9a0c-0305e82c3301": "/items/:id",
+		"/blobs/0123456789abcdef0123":                 "/blobs/:id",
+		"/users/:user_id":                             "/users/:user_id",
+		"/v2/health":                                  "/v2/health",
+		"/users/deadbeef":                             "/users/deadbeef",
+	}
+	for path, expected := range tests {
+		if got := NormalizePath(path); got != expected {
+			t.Errorf("NormalizePath(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	truncate := Truncate(4)
+
+	tests := map[string]string{
+		"abc":    "abc",
+		"abcdef": "abcd",
+		"abcé":   "abc", // é takes two bytes and is not split
+	}
+	for value, expected := range tests {
+		if got := truncate(value); got != expected {
+			t.Errorf("Truncate(4)(%q) = %q, expected %q", value, got, expected)
+		}
+	}
+	// The limit is the same on every call
+	if got := truncate("abcdef"); got != "abcd" {
+		t.Errorf("Expected the limit to be kept across calls, got %q", got)
+	}
+
+	for _, max := range []int{0, -1} {
+		if got := Truncate(max)("abc"); got != "" {
+			t.Errorf("Truncate(%d)(%q) = %q, expected an empty value", max, "abc", got)
+		}
+	}
+}
+
+func TestTagPolicyLimit(t *testing.T) {
+	policy := TagPolicy{MaxValuesPerKey: 10, MaxValues: map[string]int{"user_id": 2, "route": 0}}
+
+	if limit := policy.Limit("method"); limit != 10 {
+		t.Errorf("Expected the default limit, got %d", limit)
+	}
+	if limit := policy.Limit("user_id"); limit != 2 {
+		t.Errorf("Expected the limit of user_id, got %d", limit)
+	}
+	if limit := policy.Limit("route"); limit != 0 {
+		t.Errorf("Expected no limit for route, got %d", limit)
+	}
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"maps"
+	"slices"
+)
+
+// Tag is a key-value pair attached to profiling samples
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Tags is an ordered set of profiling tags, applied together by TagWrapperLabels
+type Tags []Tag
+
+// NewTags creates tags from alternating keys and values, like pprof.Labels.
+// A key given twice keeps its first position and its last value.
+// It panics if given an odd number of strings.
+func NewTags(keyValues ...string) Tags {
+	if len(keyValues)%2 == 1 {
+		panic("core.NewTags: odd argument count")
+	}
+
+	var tags Tags
+	for i := 0; i < len(keyValues); i += 2 {
+		tags = tags.With(keyValues[i], keyValues[i+1])
+	}
+	return tags
+}
+
+// TagsFromMap creates tags from a map, ordered by key as maps have no order
+func TagsFromMap(m map[string]string) Tags {
+	tags := make(Tags, 0, len(m))
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		tags = append(tags, Tag{Key: key, Value: m[key]})
+	}
+	return tags
+}
+
+// With returns the tags with key set to value, replacing an existing value in place
+// or appending the tag. The receiver is not modified.
+func (t Tags) With(key, value string) Tags {
+	if i := t.index(key); i >= 0 {
+		t = slices.Clone(t)
+		t[i].Value = value
+		return t
+	}
+	return append(slices.Clip(t), Tag{Key: key, Value: value})
+}
+
+// Merge returns the tags with every tag of other applied in order through With
+func (t Tags) Merge(other Tags) Tags {
+	for _, tag := range other {
+		t = t.With(tag.Key, tag.Value)
+	}
+	return t
+}
+
+// Get returns the value of key and whether it is set
+func (t Tags) Get(key string) (string, bool) {
+	if i := t.index(key); i >= 0 {
+		return t[i].Value, true
+	}
+	return "", false
+}
+
+// KeyValues returns the tags as alternating keys and values, e.g. for pprof.Labels
+func (t Tags) KeyValues() []string {
+	keyValues := make([]string, 0, 2*len(t))
+	for _, tag := range t {
+		keyValues = append(keyValues, tag.Key, tag.Value)
+	}
+	return keyValues
+}
+
+// index returns the position of key, or -1 if it is not set
+func (t Tags) index(key string) int {
+	return slices.IndexFunc(t, func(tag Tag) bool { return tag.Key == key })
+}
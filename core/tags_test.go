@@ -0,0 +1,50 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewTags(t *testing.T) {
+	tags := NewTags("route", "/", "method", "GET", "route", "/users")
+
+	expected := Tags{{"route", "/users"}, {"method", "GET"}}
+	if !slices.Equal(tags, expected) {
+		t.Errorf("Expected %v, got %v", expected, tags)
+	}
+	if keyValues := tags.KeyValues(); !slices.Equal(keyValues, []string{"route", "/users", "method", "GET"}) {
+		t.Errorf("Unexpected key-values %v", keyValues)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("NewTags should panic on an odd argument count")
+		}
+	}()
+	NewTags("route")
+}
+
+func TestTagsFromMap(t *testing.T) {
+	tags := TagsFromMap(map[string]string{"b": "2", "a": "1", "c": "3"})
+
+	expected := Tags{{"a", "1"}, {"b", "2"}, {"c", "3"}}
+	if !slices.Equal(tags, expected) {
+		t.Errorf("Expected tags ordered by key %v, got %v", expected, tags)
+	}
+}
+
+func TestTagsMerge(t *testing.T) {
+	base := NewTags("service", "api", "route", "/")
+	merged := base.Merge(NewTags("route", "/users", "method", "GET"))
+
+	expected := Tags{{"service", "api"}, {"route", "/users"}, {"method", "GET"}}
+	if !slices.Equal(merged, expected) {
+		t.Errorf("Expected %v, got %v", expected, merged)
+	}
+	if value, _ := base.Get("route"); value != "/" {
+		t.Errorf("Merge should not modify the receiver, got route=%s", value)
+	}
+	if _, ok := merged.Get("missing"); ok {
+		t.Error("Get should report a missing key")
+	}
+}
@@ -1,13 +1,26 @@
 package core
 
+import "time"
+
 // BackendType represents the type of profiling backend
 type BackendType string
 
 const (
 	PyroscopeBackend BackendType = "pyroscope"
 	PprofBackend     BackendType = "pprof"
+	HTTPBackend      BackendType = "http" // Serves net/http/pprof on ServerAddress
 )
 
+// IsValid returns whether the backend type is supported
+func (b BackendType) IsValid() bool {
+	switch b {
+	case PyroscopeBackend, PprofBackend, HTTPBackend:
+		return true
+	default:
+		return false
+	}
+}
+
 // ProfileType represents different types of profiles
 type ProfileType string
 
@@ -24,6 +37,17 @@ const (
 	ProfileBlockDuration ProfileType = "block_duration"
 )
 
+// IsValid returns whether the profile type is known
+func (pt ProfileType) IsValid() bool {
+	switch pt {
+	case ProfileCPU, ProfileAllocObjects, ProfileAllocSpace, ProfileInuseObjects, ProfileInuseSpace,
+		ProfileGoroutines, ProfileMutexCount, ProfileMutexDuration, ProfileBlockCount, ProfileBlockDuration:
+		return true
+	default:
+		return false
+	}
+}
+
 // ProfilingState represents the initial state of profiling
 type ProfilingState string
 
@@ -32,6 +56,11 @@ const (
 	ProfilingDisabled ProfilingState = "disabled"
 )
 
+// IsValid returns whether the profiling state is known
+func (s ProfilingState) IsValid() bool {
+	return s == ProfilingEnabled || s == ProfilingDisabled
+}
+
 // LegacyType represents the type of profiler for backward compatibility
 type LegacyType string
 
@@ -39,3 +68,46 @@ const (
 	LegacyTypePyroscope LegacyType = "pyroscope"
 	LegacyTypePprof     LegacyType = "pprof"
 )
+
+// MemorySource represents where memory usage is read from
+type MemorySource string
+
+const (
+	MemorySourceRuntime     MemorySource = "runtime"      // Memory mapped by the Go runtime, like runtime.MemStats.Sys
+	MemorySourceHeapLive    MemorySource = "heap_live"    // Live heap as of the last garbage collection
+	MemorySourceRSSEstimate MemorySource = "rss_estimate" // Memory mapped by the Go runtime minus heap released to the OS
+	MemorySourceCgroup      MemorySource = "cgroup"       // memory.current (v2) or memory.usage_in_bytes (v1)
+	MemorySourceRSS         MemorySource = "rss"          // VmRSS from /proc/self/status
+)
+
+// IsValid returns whether the memory source is known
+func (s MemorySource) IsValid() bool {
+	switch s {
+	case MemorySourceRuntime, MemorySourceHeapLive, MemorySourceRSSEstimate, MemorySourceCgroup, MemorySourceRSS:
+		return true
+	default:
+		return false
+	}
+}
+
+// OverheadStats is an estimate of the cost of profiling attributed to a profiler.
+// CPU time is measured as the time spent encoding, writing and sending profiles, so it
+// is an upper bound of the CPU actually used.
+type OverheadStats struct {
+	CPUTime       time.Duration // Time spent encoding, writing, serving and uploading profiles
+	BytesBuffered int64         // Profile data currently held in memory waiting to be sent
+	BytesWritten  int64         // Profile data written to files or served over HTTP
+	BytesUploaded int64         // Profile data sent to a remote server
+	Uploads       int64         // Upload requests sent to a remote server
+}
+
+// Add returns the sum of two overhead statistics
+func (s OverheadStats) Add(other OverheadStats) OverheadStats {
+	return OverheadStats{
+		CPUTime:       s.CPUTime + other.CPUTime,
+		BytesBuffered: s.BytesBuffered + other.BytesBuffered,
+		BytesWritten:  s.BytesWritten + other.BytesWritten,
+		BytesUploaded: s.BytesUploaded + other.BytesUploaded,
+		Uploads:       s.Uploads + other.Uploads,
+	}
+}
@@ -20,21 +20,13 @@ func ProfilingMiddleware() echo.MiddlewareFunc {
 			route := c.Path()
 			method := c.Request().Method
 
-			// Add tags to the current request context
-			if err := profilego.AddTag("http_method", method); err != nil {
-				// Log but don't fail
-				log.Printf("failed to add tag: %v", err)
-			}
-
-			// Use TagWrapper to wrap the entire handler execution
-			// Pass nil to use the profiler context automatically
-			return profilego.TagWrapper(
-				nil,
-				"route",
-				route,
+			// Use TagWrapperLabels to wrap the entire handler execution with all tags at once
+			return profilego.TagWrapperLabels(
+				c.Request().Context(),
+				core.NewTags("route", route, "http_method", method),
 				func(ctx context.Context) error {
-					// Execute the actual handler
-					// ctx is the profiler context passed automatically
+					// Hand the labelled context to the handler so its own tags are added to these
+					c.SetRequest(c.Request().WithContext(ctx))
 					return next(c)
 				},
 			)
@@ -46,10 +38,12 @@ func ProfilingMiddleware() echo.MiddlewareFunc {
 func UserHandler(c echo.Context) error {
 	userID := c.Param("user_id")
 
-	// Additional tag for this specific handler
-	if err := profilego.AddTag("user_id", userID); err != nil {
+	// Additional tag for the rest of this handler, released when it returns
+	_, release, err := profilego.AddTagScoped(c.Request().Context(), "user_id", userID)
+	if err != nil {
 		log.Printf("failed to add tag: %v", err)
 	}
+	defer release()
 
 	return c.JSON(http.StatusOK, map[string]string{
 		"user_id": userID,
@@ -62,14 +56,13 @@ func ConvertHandler(c echo.Context) error {
 	conversionType := c.QueryParam("type")
 
 	// Wrap the conversion logic with tags
-	// Pass nil to use the profiler context automatically
 	return profilego.TagWrapper(
-		nil,
+		c.Request().Context(),
 		"conversion_type",
 		conversionType,
 		func(ctx context.Context) error {
-			// Simulate some work
-			if err := profilego.AddTag("status", "processing"); err != nil {
+			// Tag the remaining work; TagWrapper restores the previous tags when it returns
+			if _, err := profilego.AddTag(ctx, "status", "processing"); err != nil {
 				log.Printf("failed to add tag: %v", err)
 			}
 
@@ -95,6 +88,14 @@ func main() {
 			"service": "api-server",
 			"env":     "development",
 		},
+		// Keep tag cardinality in check: user IDs are unbounded, so only the first 100
+		// get their own series and the rest are counted under "other"
+		TagPolicy: &core.TagPolicy{
+			MaxValues: map[string]int{"user_id": 100},
+			Normalizers: map[string][]core.TagNormalizer{
+				"conversion_type": {core.Lowercase, core.Truncate(32)},
+			},
+		},
 	}
 
 	profilerCtx, err := profilego.InitWithConfig(ctx, cfg)
@@ -32,16 +32,17 @@ func main() {
 	}
 	defer profilego.Stop()
 
-	// Add tags to the profiling context using the new API
-	if err := profilego.AddTag("request_id", "12345"); err != nil {
+	// Add a tag to the calling goroutine; the returned context carries it
+	tagCtx, err := profilego.AddTag(profilerCtx, "request_id", "12345")
+	if err != nil {
 		log.Fatalf("failed to add tag: %v", err)
 	}
 
 	// Use TagWrapper to execute code with additional tags
-	// Can pass nil to use the profiler context automatically
-	if err := profilego.TagWrapper(nil, "route", "api_converter", func(ctx context.Context) error {
+	// Passing tagCtx keeps the request_id tag; pass nil to use the profiler context
+	if err := profilego.TagWrapper(tagCtx, "route", "api_converter", func(ctx context.Context) error {
 		// Your handler logic here
-		// ctx is the profiler context passed automatically
+		// ctx carries both the request_id and the route tags
 		time.Sleep(100 * time.Millisecond)
 		return nil
 	}); err != nil {
@@ -5,12 +5,14 @@ go 1.24.0
 require (
 	dario.cat/mergo v1.0.2
 	github.com/go-playground/validator/v10 v10.28.0
+	github.com/google/pprof v0.0.0-20260115054156-294ebfa9ad83
 	github.com/grafana/otel-profiling-go v0.5.1
 	github.com/grafana/pyroscope-go v1.2.7
 	github.com/labstack/echo/v4 v4.13.4
 	go.opentelemetry.io/otel v1.38.0
 	go.opentelemetry.io/otel/sdk v1.38.0
 	go.opentelemetry.io/otel/trace v1.38.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,187 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/wasilak/profilego/core"
+	"github.com/wasilak/profilego/memory"
+)
+
+// defaultMemoryResumeRatio is used when MemoryResumeRatio is not set
+const defaultMemoryResumeRatio = 0.9
+
+// guardStages lists the profile types the memory guard suspends, most expensive first.
+// One more stage is suspended on every check over the limit and resumed in reverse order.
+var guardStages = [][]core.ProfileType{
+	{core.ProfileCPU},
+	{core.ProfileAllocObjects, core.ProfileAllocSpace},
+	{core.ProfileBlockCount, core.ProfileBlockDuration},
+}
+
+// MemoryAction is an action taken by the memory guard
+type MemoryAction string
+
+const (
+	MemorySuspended MemoryAction = "suspended"
+	MemoryResumed   MemoryAction = "resumed"
+)
+
+// MemoryEvent describes profile types suspended or resumed by the memory guard
+type MemoryEvent struct {
+	Action       MemoryAction
+	ProfileTypes []core.ProfileType
+	UsageMB      float64
+	LimitMB      int64
+}
+
+// MemoryListener is called after the memory guard suspends or resumes profile types
+type MemoryListener func(event MemoryEvent)
+
+// memoryGuard suspends expensive profile types while memory usage exceeds MemoryLimitMB
+type memoryGuard struct {
+	monitor   *memory.MemoryMonitor
+	limitMB   int64 // effective limit, resolved when the monitor is started
+	stage     int   // number of guardStages currently suspended
+	listeners []MemoryListener
+}
+
+// OnMemoryEvent registers a listener called after the memory guard suspends or resumes profile types.
+// Listeners are called synchronously while the manager is locked and must not call back into it.
+func (pm *ProfilerManager) OnMemoryEvent(listener MemoryListener) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.guard.listeners = append(pm.guard.listeners, listener)
+}
+
+// startGuard starts monitoring memory usage if a memory limit is set, replacing a running monitor
+// Must be called with pm.mu held
+func (pm *ProfilerManager) startGuard() {
+	if pm.guard.monitor != nil {
+		pm.guard.monitor.Stop()
+		pm.guard.monitor = nil
+	}
+
+	options := memory.FromConfig(pm.config)
+	pm.guard.limitMB = options.Limit()
+	if pm.guard.limitMB <= 0 {
+		return
+	}
+
+	monitor := options.Monitor()
+	monitor.SetInterval(pm.config.MemoryCheckInterval)
+	if pm.memoryUsage != nil {
+		monitor.SetUsageFunc(pm.memoryUsage)
+	}
+	monitor.OnSample(func(usageMB float64) {
+		pm.checkMemory(monitor, usageMB)
+	})
+	monitor.Start()
+
+	pm.guard.monitor = monitor
+}
+
+// stopGuard stops monitoring memory usage and resumes every suspended profile type
+// Must be called with pm.mu held
+func (pm *ProfilerManager) stopGuard(ctx context.Context) error {
+	if pm.guard.monitor != nil {
+		pm.guard.monitor.Stop()
+		pm.guard.monitor = nil
+	}
+
+	var errs []error
+	for pm.guard.stage > 0 {
+		pm.guard.stage--
+		errs = append(errs, pm.setSuspended(ctx, guardStages[pm.guard.stage], false))
+	}
+	return errors.Join(errs...)
+}
+
+// checkMemory suspends the next stage of profile types when usage exceeds the limit and
+// resumes the last suspended stage once usage drops below the resume threshold
+func (pm *ProfilerManager) checkMemory(monitor *memory.MemoryMonitor, usageMB float64) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	// Samples of a replaced monitor may still arrive
+	limitMB := pm.guard.limitMB
+	if pm.guard.monitor != monitor || !pm.state.Is(core.StateRunning) {
+		return
+	}
+
+	ratio := pm.config.MemoryResumeRatio
+	if ratio <= 0 {
+		ratio = defaultMemoryResumeRatio
+	}
+
+	event := MemoryEvent{UsageMB: usageMB, LimitMB: limitMB}
+	switch {
+	case usageMB > float64(limitMB) && pm.guard.stage < len(guardStages):
+		event.Action, event.ProfileTypes = MemorySuspended, guardStages[pm.guard.stage]
+		pm.guard.stage++
+		slog.Warn("profilego - memory limit exceeded, suspending profile types", "types", event.ProfileTypes, "usage_mb", usageMB, "limit_mb", limitMB)
+	case usageMB < float64(limitMB)*ratio && pm.guard.stage > 0:
+		pm.guard.stage--
+		event.Action, event.ProfileTypes = MemoryResumed, guardStages[pm.guard.stage]
+		slog.Info("profilego - memory usage back under the limit, resuming profile types", "types", event.ProfileTypes, "usage_mb", usageMB, "limit_mb", limitMB)
+	default:
+		return
+	}
+
+	ctx, cancel := pm.withTimeout(context.Background())
+	defer cancel()
+
+	if err := pm.setSuspended(ctx, event.ProfileTypes, event.Action == MemorySuspended); err != nil {
+		slog.Error("profilego - failed to apply memory guard action", "action", event.Action, "error", err)
+	}
+
+	for _, listener := range pm.guard.listeners {
+		listener(event)
+	}
+}
+
+// setSuspended suspends or resumes the given profile types on every profiler supporting it.
+// Types still suspended by the memory guard or the overhead budget are not resumed.
+// Must be called with pm.mu held
+func (pm *ProfilerManager) setSuspended(ctx context.Context, types []core.ProfileType, suspended bool) error {
+	if !suspended {
+		stillSuspended := pm.suspendedTypes()
+		types = slices.DeleteFunc(slices.Clone(types), func(pt core.ProfileType) bool {
+			return slices.Contains(stillSuspended, pt)
+		})
+	}
+
+	var errs []error
+	for _, name := range pm.names() {
+		suspender, ok := pm.profilers[name].(core.ProfileTypeSuspender)
+		if !ok {
+			continue
+		}
+
+		var err error
+		if suspended {
+			err = suspender.SuspendProfileTypes(ctx, types)
+		} else {
+			err = suspender.ResumeProfileTypes(ctx, types)
+		}
+		if err != nil {
+			errs = append(errs, &ManagerError{Operation: "MemoryGuard", Message: "failed to update profile types of " + name, Err: err})
+			pm.failIfStopped(name, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// suspendedTypes returns the profile types currently suspended by the memory guard or the overhead budget
+// Must be called with pm.mu held
+func (pm *ProfilerManager) suspendedTypes() []core.ProfileType {
+	types := slices.Concat(guardStages[:pm.guard.stage]...)
+	for _, pt := range pm.overheadSuspended() {
+		if !slices.Contains(types, pt) {
+			types = append(types, pt)
+		}
+	}
+	return types
+}
@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+// suspendingProfiler is a test profiler recording the profile types it was asked to suspend
+type suspendingProfiler struct {
+	TestProfiler
+	mu        sync.Mutex
+	suspended map[core.ProfileType]bool
+}
+
+func (s *suspendingProfiler) SuspendProfileTypes(ctx context.Context, types []core.ProfileType) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, pt := range types {
+		s.suspended[pt] = true
+	}
+	return nil
+}
+
+func (s *suspendingProfiler) ResumeProfileTypes(ctx context.Context, types []core.ProfileType) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, pt := range types {
+		delete(s.suspended, pt)
+	}
+	return nil
+}
+
+// isSuspended returns whether the profile type is suspended
+func (s *suspendingProfiler) isSuspended(pt core.ProfileType) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.suspended[pt]
+}
+
+func TestMemoryGuard(t *testing.T) {
+	var usageMB atomic.Uint64
+	usageMB.Store(200)
+
+	manager := NewProfilerManager(config.Config{
+		ApplicationName:     "test-app",
+		InitialState:        core.ProfilingEnabled,
+		MemoryLimitMB:       100,
+		MemoryCheckInterval: time.Millisecond,
+		MemoryResumeRatio:   0.8,
+	})
+	manager.memoryUsage = func() uint64 { return usageMB.Load() * 1024 * 1024 }
+
+	events := make(chan MemoryEvent, 16)
+	manager.OnMemoryEvent(func(event MemoryEvent) { events <- event })
+
+	profiler := &suspendingProfiler{TestProfiler: TestProfiler{name: "test"}, suspended: make(map[core.ProfileType]bool)}
+	manager.AddProfiler(profiler)
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	defer manager.Stop(context.Background())
+
+	// nextEvent returns the next event or fails the test
+	nextEvent := func() MemoryEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for a memory event")
+			return MemoryEvent{}
+		}
+	}
+
+	// Over the limit, the stages are suspended one per check, most expensive first
+	for _, stage := range guardStages {
+		event := nextEvent()
+		if event.Action != MemorySuspended || !slices.Equal(event.ProfileTypes, stage) || event.LimitMB != 100 {
+			t.Fatalf("Expected %v to be suspended, got %+v", stage, event)
+		}
+	}
+	if !profiler.isSuspended(core.ProfileCPU) || !profiler.isSuspended(core.ProfileBlockDuration) {
+		t.Error("Profiler should have been asked to suspend the profile types")
+	}
+	if suspended := manager.Status().Suspended; len(suspended) != 5 {
+		t.Errorf("Expected 5 suspended profile types in status, got %v", suspended)
+	}
+
+	// Between the resume threshold and the limit nothing changes
+	usageMB.Store(90)
+	select {
+	case event := <-events:
+		t.Fatalf("Expected no action above the resume threshold, got %+v", event)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Under the threshold, the stages are resumed in reverse order
+	usageMB.Store(50)
+	for i := len(guardStages) - 1; i >= 0; i-- {
+		event := nextEvent()
+		if event.Action != MemoryResumed || !slices.Equal(event.ProfileTypes, guardStages[i]) {
+			t.Fatalf("Expected %v to be resumed, got %+v", guardStages[i], event)
+		}
+	}
+	if profiler.isSuspended(core.ProfileCPU) {
+		t.Error("CPU should have been resumed")
+	}
+}
+
+func TestMemoryGuardSuspendsNewProfilers(t *testing.T) {
+	manager := NewProfilerManager(config.Config{ApplicationName: "test-app"})
+	manager.guard.stage = 1
+
+	profiler := &suspendingProfiler{TestProfiler: TestProfiler{name: "test"}, suspended: make(map[core.ProfileType]bool)}
+	manager.AddProfiler(profiler)
+
+	if !profiler.isSuspended(core.ProfileCPU) || profiler.isSuspended(core.ProfileAllocSpace) {
+		t.Errorf("Expected only CPU to be suspended, got %v", profiler.suspended)
+	}
+}
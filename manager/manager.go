@@ -2,6 +2,9 @@ package manager
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"slices"
 	"sync"
 
 	"github.com/wasilak/profilego/config"
@@ -14,25 +17,33 @@ type ProfilerManager struct {
 	mu        sync.RWMutex
 	config    config.Config
 	profilers map[string]core.Profiler
-	running   bool
-	ctx       context.Context
-	cancel    context.CancelFunc
+	machines  map[string]*core.StateMachine // lifecycle state of each profiler
+	state     *core.StateMachine            // lifecycle state of the manager itself
+	listeners []core.StateListener
+	backends  []string            // names of the profilers created from config
+	restarts  map[string]*restart // automatic restarts of each profiler
+	guard     memoryGuard
+	overhead  overheadBudget
+	tags      globalTags
+	// memoryUsage replaces how the memory guard measures usage, in bytes, when set
+	memoryUsage func() uint64
 }
 
 // NewProfilerManager creates a new profiler manager
 func NewProfilerManager(cfg config.Config) *ProfilerManager {
-	ctx, cancel := context.WithCancel(context.Background())
-
 	return &ProfilerManager{
 		config:    cfg,
 		profilers: make(map[string]core.Profiler),
-		ctx:       ctx,
-		cancel:    cancel,
+		machines:  make(map[string]*core.StateMachine),
+		state:     core.NewStateMachine("manager"),
+		restarts:  make(map[string]*restart),
 	}
 }
 
-// Init initializes and starts the configured profiler(s)
-func (pm *ProfilerManager) Init() error {
+// Init initializes and starts the configured profiler(s).
+// Like all lifecycle operations it honours ctx, bounded by the configured Timeout.
+// With AllowDegraded, profilers failing to start do not fail Init and are restarted in the background.
+func (pm *ProfilerManager) Init(ctx context.Context) error {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
@@ -40,24 +51,47 @@ func (pm *ProfilerManager) Init() error {
 		return err
 	}
 
-	// Create the appropriate profiler based on config
-	profiler, err := pm.createProfiler()
-	if err != nil {
+	ctx, cancel := pm.withTimeout(ctx)
+	defer cancel()
+
+	if err := pm.state.Transition(core.StateStarting); err != nil {
 		return err
 	}
 
-	name := profiler.Name()
-	pm.profilers[name] = profiler
+	// Create a profiler for every configured backend
+	warnCPUBackends(pm.config)
+	backends := pm.config.EffectiveBackends()
+	names := make([]string, 0, len(backends))
+	for _, b := range backends {
+		profiler, err := pm.createProfiler(b.Config)
+		if err != nil {
+			return pm.state.Fail(err)
+		}
 
-	// Start the profiler if initial state is enabled
+		pm.register(b.Name, profiler)
+		names = append(names, b.Name)
+	}
+	// Assigned rather than appended, so retrying a failed Init does not list a backend twice
+	pm.backends = names
+
+	// Start the profilers if initial state is enabled
 	if pm.config.InitialState == core.ProfilingEnabled {
-		if err := profiler.Start(pm.ctx); err != nil {
-			return err
+		if err := pm.startAll(ctx, "Init", pm.backends); err != nil {
+			return pm.state.Fail(err)
 		}
 	}
 
-	pm.running = true
-	return nil
+	pm.startGuard()
+	pm.startOverhead()
+	return pm.state.Transition(core.StateRunning)
+}
+
+// warnCPUBackends logs the backends left without the CPU profile because another one collects it
+func warnCPUBackends(cfg config.Config) {
+	if owner, others := cfg.CPUBackends(); len(others) > 0 {
+		slog.Warn("profilego - only one backend can collect the CPU profile, skipping it on the others",
+			"backend", owner, "skipped", others)
+	}
 }
 
 // AddProfiler adds an additional profiler to the manager
@@ -70,61 +104,163 @@ func (pm *ProfilerManager) AddProfiler(profiler core.Profiler) error {
 		return &ManagerError{Operation: "AddProfiler", Message: "profiler with name " + name + " already exists"}
 	}
 
-	pm.profilers[name] = profiler
-	if pm.running && pm.config.InitialState == core.ProfilingEnabled {
-		return profiler.Start(pm.ctx)
+	pm.register(name, profiler)
+
+	ctx, cancel := pm.withTimeout(context.Background())
+	defer cancel()
+	return pm.activate(ctx, name)
+}
+
+// Start starts all managed profilers, including paused ones.
+// Start is transactional: if a profiler fails to start, the profilers started by this call are
+// stopped again and the errors are returned joined. A manager can be started again after Stop.
+func (pm *ProfilerManager) Start(ctx context.Context) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if !pm.state.Is(core.StateRunning) {
+		if err := pm.state.Transition(core.StateStarting); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	ctx, cancel := pm.withTimeout(ctx)
+	defer cancel()
+
+	if err := pm.startAll(ctx, "Start", pm.names()); err != nil {
+		return pm.state.Fail(err)
+	}
+
+	if pm.state.Is(core.StateRunning) {
+		return nil
+	}
+	pm.startGuard()
+	pm.startOverhead()
+	return pm.state.Transition(core.StateRunning)
 }
 
-// Start starts all managed profilers
-func (pm *ProfilerManager) Start() error {
-	pm.mu.RLock()
-	defer pm.mu.RUnlock()
+// Stop stops all managed profilers, returning the errors of all that failed joined.
+// A profiler that does not flush its final profiles before ctx is done or the configured
+// Timeout elapses fails with a *core.TimeoutError.
+func (pm *ProfilerManager) Stop(ctx context.Context) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
 
-	for name, profiler := range pm.profilers {
-		if err := profiler.Start(pm.ctx); err != nil {
-			return &ManagerError{Operation: "Start", Message: "failed to start profiler " + name + ": " + err.Error()}
+	if pm.state.Is(core.StateStopped) {
+		return nil
+	}
+	if err := pm.state.Transition(core.StateStopping); err != nil {
+		return err
+	}
+
+	ctx, cancel := pm.withTimeout(ctx)
+	defer cancel()
+
+	pm.cancelRestarts()
+
+	var errs []error
+	for _, name := range pm.names() {
+		if err := pm.stopProfiler(ctx, name); err != nil {
+			errs = append(errs, &ManagerError{Operation: "Stop", Message: "failed to stop profiler " + name, Err: err})
 		}
 	}
+	errs = append(errs, pm.stopGuard(ctx), pm.stopOverhead(ctx))
 
+	if err := errors.Join(errs...); err != nil {
+		return pm.state.Fail(err)
+	}
+	return pm.state.Transition(core.StateStopped)
+}
+
+// Pause temporarily stops all running profilers
+func (pm *ProfilerManager) Pause(ctx context.Context) error {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
-	pm.running = true
-	return nil
+
+	if pm.state.Is(core.StatePaused) {
+		return nil
+	}
+	if err := pm.state.Transition(core.StatePaused); err != nil {
+		return err
+	}
+
+	ctx, cancel := pm.withTimeout(ctx)
+	defer cancel()
+
+	var errs []error
+	for _, name := range pm.names() {
+		if err := pm.pauseProfiler(ctx, name); err != nil {
+			errs = append(errs, &ManagerError{Operation: "Pause", Message: "failed to pause profiler " + name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
 }
 
-// Stop stops all managed profilers
-func (pm *ProfilerManager) Stop() error {
-	pm.mu.RLock()
-	defer pm.mu.RUnlock()
+// Resume resumes the profilers paused by Pause
+func (pm *ProfilerManager) Resume(ctx context.Context) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if !pm.state.Is(core.StatePaused) {
+		return nil
+	}
+
+	ctx, cancel := pm.withTimeout(ctx)
+	defer cancel()
 
-	var lastErr error
-	for name, profiler := range pm.profilers {
-		if err := profiler.Stop(pm.ctx); err != nil {
-			lastErr = &ManagerError{Operation: "Stop", Message: "failed to stop profiler " + name + ": " + err.Error()}
+	var errs []error
+	for _, name := range pm.names() {
+		if err := pm.resumeProfiler(ctx, name); err != nil {
+			errs = append(errs, &ManagerError{Operation: "Resume", Message: "failed to resume profiler " + name, Err: err})
 		}
 	}
 
+	errs = append(errs, pm.state.Transition(core.StateRunning))
+	return errors.Join(errs...)
+}
+
+// OnStateChange registers a listener called after every state change of a managed profiler.
+// Listeners are called synchronously while the manager is locked and must not call back into it.
+func (pm *ProfilerManager) OnStateChange(listener core.StateListener) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
-	pm.running = false
+	pm.listeners = append(pm.listeners, listener)
+}
 
-	// Cancel the context to signal all operations to stop
-	pm.cancel()
+// State returns the lifecycle state of the manager
+func (pm *ProfilerManager) State() core.State {
+	return pm.state.State()
+}
 
-	return lastErr
+// States returns the lifecycle state of every managed profiler by name
+func (pm *ProfilerManager) States() map[string]core.State {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	states := make(map[string]core.State, len(pm.machines))
+	for name, machine := range pm.machines {
+		states[name] = machine.State()
+	}
+	return states
 }
 
-// IsRunning returns whether the profiler manager is running
+// IsRunning returns whether the profiler manager is running and not paused
 func (pm *ProfilerManager) IsRunning() bool {
+	return pm.state.Is(core.StateRunning)
+}
+
+// IsPaused returns whether the profiler manager is paused
+func (pm *ProfilerManager) IsPaused() bool {
+	return pm.state.Is(core.StatePaused)
+}
+
+// Config returns the current configuration
+func (pm *ProfilerManager) Config() config.Config {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
-	return pm.running
+	return pm.config
 }
 
-
 // AppName returns the application name from config
 func (pm *ProfilerManager) AppName() string {
 	pm.mu.RLock()
@@ -146,47 +282,279 @@ func (pm *ProfilerManager) Backend() core.BackendType {
 	return pm.config.Backend
 }
 
-// createProfiler creates the appropriate profiler based on configuration
-func (pm *ProfilerManager) createProfiler() (core.Profiler, error) {
-	switch pm.config.Backend {
-	case core.PyroscopeBackend:
-		return pm.createPyroscopeProfiler()
-	case core.PprofBackend:
-		return pm.createPprofProfiler()
-	default:
-		return nil, &ManagerError{Operation: "createProfiler", Message: "unsupported backend type: " + string(pm.config.Backend)}
+// withTimeout returns a context derived from ctx that is done after the configured Timeout,
+// the default one if not set, or earlier if ctx is. A nil ctx is treated as context.Background().
+// Must be called with pm.mu held
+func (pm *ProfilerManager) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	timeout := pm.config.Timeout
+	if timeout <= 0 {
+		timeout = config.DefaultConfig.Timeout
+	}
+	return context.WithTimeout(ctx, timeout)
 }
 
-// validateConfig validates the configuration before initialization
-func (pm *ProfilerManager) validateConfig() error {
-	if pm.config.ApplicationName == "" {
-		return &ManagerError{Operation: "validateConfig", Message: "application name not provided"}
+// active returns whether the manager lifecycle has been started, paused or not
+func (pm *ProfilerManager) active() bool {
+	return pm.state.Is(core.StateRunning, core.StatePaused)
+}
+
+// register adds a profiler, following its own state machine if it reports one or a fresh one
+// driven by the manager otherwise, and reports its state changes to the listeners
+// Must be called with pm.mu held
+func (pm *ProfilerManager) register(name string, profiler core.Profiler) {
+	pm.forgetRestarts(name)
+
+	var machine *core.StateMachine
+	if reporter, ok := profiler.(core.StateReporter); ok {
+		machine = reporter.StateMachine()
+	} else {
+		machine = core.NewStateMachine(name)
+	}
+	// The profiler may be named differently from its backend
+	machine.OnStateChange(func(_ string, from, to core.State, err error) {
+		pm.supervise(name, from, to, err)
+		pm.notify(name, from, to, err)
+	})
+
+	pm.profilers[name] = profiler
+	pm.machines[name] = machine
+
+	// Profile types suspended under memory pressure or over the overhead budget stay suspended
+	// for new profilers, as does reduced sampling
+	if suspender, ok := profiler.(core.ProfileTypeSuspender); ok {
+		if types := pm.suspendedTypes(); len(types) > 0 {
+			if err := suspender.SuspendProfileTypes(context.Background(), types); err != nil {
+				slog.Error("profilego - failed to suspend profile types", "profiler", name, "error", err)
+			}
+		}
+	}
+	if reducer, ok := profiler.(core.SamplingReducer); ok && pm.overhead.stage > 0 {
+		if err := reducer.ReduceSampling(context.Background(), true); err != nil {
+			slog.Error("profilego - failed to reduce sampling", "profiler", name, "error", err)
+		}
 	}
 
-	if pm.config.ServerAddress == "" && pm.config.Backend != core.PprofBackend {
-		return &ManagerError{Operation: "validateConfig", Message: "server address not provided for backend: " + string(pm.config.Backend)}
+	// So do static tags set or removed at runtime
+	if tagger, ok := profiler.(core.GlobalTagger); ok {
+		pm.applyGlobalTags(name, tagger)
 	}
+}
 
+// notify forwards a profiler state change to the listeners
+// Called with pm.mu held by the operation causing the change
+func (pm *ProfilerManager) notify(name string, from, to core.State, err error) {
+	for _, listener := range pm.listeners {
+		listener(name, from, to, err)
+	}
+}
+
+// activate brings a newly added profiler in line with the manager lifecycle, starting it
+// when profiling is active; while the manager is paused it is started by Resume instead
+// Must be called with pm.mu held
+func (pm *ProfilerManager) activate(ctx context.Context, name string) error {
+	if !pm.state.Is(core.StateRunning) || pm.config.InitialState != core.ProfilingEnabled {
+		return nil
+	}
+	return pm.startProfiler(ctx, name)
+}
+
+// names returns the names of all profilers: those created from config in configuration
+// order, followed by the others sorted by name
+// Must be called with pm.mu held
+func (pm *ProfilerManager) names() []string {
+	names := slices.Clone(pm.backends)
+	var others []string
+	for name := range pm.profilers {
+		if !slices.Contains(pm.backends, name) {
+			others = append(others, name)
+		}
+	}
+	slices.Sort(others)
+	return append(names, others...)
+}
+
+// startAll starts the named profilers in order. If one fails, the profilers started by this
+// call are stopped again in reverse order and all errors are returned joined.
+// The rollback is not cut short by ctx, only by the configured Timeout.
+// With AllowDegraded, failing profilers are skipped and left to the supervisor instead.
+// Must be called with pm.mu held
+func (pm *ProfilerManager) startAll(ctx context.Context, operation string, names []string) error {
+	var started []string
+	for _, name := range names {
+		wasRunning := pm.machines[name].Is(core.StateRunning)
+
+		if err := pm.startProfiler(ctx, name); err != nil {
+			if pm.config.AllowDegraded {
+				slog.Warn("profilego - profiler failed to start, continuing in degraded mode", "profiler", name, "error", err)
+				continue
+			}
+
+			errs := []error{&ManagerError{Operation: operation, Message: "failed to start profiler " + name, Err: err}}
+
+			rollbackCtx, cancel := pm.withTimeout(context.WithoutCancel(ctx))
+			defer cancel()
+			for i := len(started) - 1; i >= 0; i-- {
+				if err := pm.stopProfiler(rollbackCtx, started[i]); err != nil {
+					errs = append(errs, &ManagerError{Operation: operation, Message: "failed to roll back profiler " + started[i], Err: err})
+				}
+			}
+			return errors.Join(errs...)
+		}
+
+		if !wasRunning {
+			started = append(started, name)
+		}
+	}
 	return nil
 }
 
+// startProfiler starts the named profiler unless it is already running
+// Must be called with pm.mu held
+func (pm *ProfilerManager) startProfiler(ctx context.Context, name string) error {
+	machine := pm.machines[name]
+	if machine.Is(core.StateRunning) {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return &core.TimeoutError{Name: name, Operation: "start", Err: err}
+	}
+	if pm.reportsState(name) {
+		return pm.profilers[name].Start(ctx)
+	}
+	if err := machine.Transition(core.StateStarting); err != nil {
+		return err
+	}
+	if err := pm.profilers[name].Start(ctx); err != nil {
+		return machine.Fail(err)
+	}
+	return machine.Transition(core.StateRunning)
+}
+
+// stopProfiler stops the named profiler unless it is already stopped
+// Must be called with pm.mu held
+func (pm *ProfilerManager) stopProfiler(ctx context.Context, name string) error {
+	machine := pm.machines[name]
+	if machine.Is(core.StateStopped) {
+		return nil
+	}
+	if pm.reportsState(name) {
+		return pm.profilers[name].Stop(ctx)
+	}
+	if err := machine.Transition(core.StateStopping); err != nil {
+		return err
+	}
+	if err := pm.profilers[name].Stop(ctx); err != nil {
+		return machine.Fail(err)
+	}
+	return machine.Transition(core.StateStopped)
+}
+
+// pauseProfiler pauses the named profiler if it is running
+// Must be called with pm.mu held
+func (pm *ProfilerManager) pauseProfiler(ctx context.Context, name string) error {
+	machine := pm.machines[name]
+	if !machine.Is(core.StateRunning) {
+		return nil
+	}
+	if pm.reportsState(name) {
+		return pm.profilers[name].Pause(ctx)
+	}
+	if err := pm.profilers[name].Pause(ctx); err != nil {
+		return machine.Fail(err)
+	}
+	return machine.Transition(core.StatePaused)
+}
+
+// resumeProfiler resumes the named profiler if it was paused, starts profilers
+// added while the manager was paused and retries restarts deferred by the pause
+// Must be called with pm.mu held
+func (pm *ProfilerManager) resumeProfiler(ctx context.Context, name string) error {
+	if retried, err := pm.retryDeferred(ctx, name); retried {
+		return err
+	}
+
+	machine := pm.machines[name]
+	switch {
+	case machine.Is(core.StatePaused):
+	case machine.Is(core.StateInitialized) && pm.config.InitialState == core.ProfilingEnabled:
+		return pm.startProfiler(ctx, name)
+	default:
+		return nil
+	}
+
+	if pm.reportsState(name) {
+		return pm.profilers[name].Resume(ctx)
+	}
+	if err := machine.Transition(core.StateStarting); err != nil {
+		return err
+	}
+	if err := pm.profilers[name].Resume(ctx); err != nil {
+		return machine.Fail(err)
+	}
+	return machine.Transition(core.StateRunning)
+}
+
+// reportsState returns whether the named profiler tracks its own state, which the manager
+// then follows instead of driving its machine
+// Must be called with pm.mu held
+func (pm *ProfilerManager) reportsState(name string) bool {
+	_, ok := pm.profilers[name].(core.StateReporter)
+	return ok
+}
+
+// createProfiler creates the appropriate profiler for a backend's configuration
+func (pm *ProfilerManager) createProfiler(cfg config.Config) (core.Profiler, error) {
+	switch cfg.Backend {
+	case core.PyroscopeBackend:
+		return pm.createPyroscopeProfiler(cfg)
+	case core.PprofBackend:
+		return pm.createPprofProfiler(cfg)
+	case core.HTTPBackend:
+		return pm.createHTTPProfiler(cfg)
+	default:
+		return nil, &ManagerError{Operation: "createProfiler", Message: "unsupported backend type: " + string(cfg.Backend)}
+	}
+}
+
+// validateConfig validates the configuration before initialization
+func (pm *ProfilerManager) validateConfig() error {
+	return pm.config.Validate()
+}
+
 // createPyroscopeProfiler creates a Pyroscope profiler
-func (pm *ProfilerManager) createPyroscopeProfiler() (core.Profiler, error) {
-	return profiler.NewPyroscopeProfiler(pm.config)
+func (pm *ProfilerManager) createPyroscopeProfiler(cfg config.Config) (core.Profiler, error) {
+	return profiler.NewPyroscopeProfiler(cfg)
 }
 
 // createPprofProfiler creates a pprof profiler
-func (pm *ProfilerManager) createPprofProfiler() (core.Profiler, error) {
-	return profiler.NewPprofProfiler(pm.config)
+func (pm *ProfilerManager) createPprofProfiler(cfg config.Config) (core.Profiler, error) {
+	return profiler.NewPprofProfiler(cfg)
+}
+
+// createHTTPProfiler creates a profiler serving net/http/pprof
+func (pm *ProfilerManager) createHTTPProfiler(cfg config.Config) (core.Profiler, error) {
+	return profiler.NewHTTPProfiler(cfg)
 }
 
 // ManagerError represents an error in the profiler manager
+// Err holds the underlying error, if any, and is reachable through errors.Is and errors.As
 type ManagerError struct {
 	Operation string
 	Message   string
+	Err       error
 }
 
 func (e *ManagerError) Error() string {
+	if e.Err != nil {
+		return "manager error (" + e.Operation + "): " + e.Message + ": " + e.Err.Error()
+	}
 	return "manager error (" + e.Operation + "): " + e.Message
 }
+
+// Unwrap returns the underlying error
+func (e *ManagerError) Unwrap() error {
+	return e.Err
+}
@@ -2,10 +2,17 @@ package manager
 
 import (
 	"context"
+	"errors"
+	"maps"
+	"net"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/wasilak/profilego/config"
 	"github.com/wasilak/profilego/core"
+	"github.com/wasilak/profilego/memory"
 )
 
 // TestProfiler is a test implementation of the Profiler interface
@@ -59,8 +66,8 @@ func TestNewProfilerManager(t *testing.T) {
 		t.Errorf("Expected empty profilers map, got %d profilers", len(manager.profilers))
 	}
 
-	if manager.running {
-		t.Error("Manager should not be running initially")
+	if manager.State() != core.StateInitialized {
+		t.Errorf("Manager should be initialized, got %s", manager.State())
 	}
 }
 
@@ -102,14 +109,14 @@ func TestIsRunning(t *testing.T) {
 		t.Error("Manager should not be running initially")
 	}
 
-	// Set running to true manually for test
-	manager.mu.Lock()
-	manager.running = true
-	manager.mu.Unlock()
+	// Start with no profilers to reach the running state
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
 
 	// Should be running now
 	if !manager.IsRunning() {
-		t.Error("Manager should be running after setting to true")
+		t.Error("Manager should be running after Start")
 	}
 }
 
@@ -163,3 +170,395 @@ func TestConcurrentAccess(t *testing.T) {
 		}
 	}
 }
+
+// errStart is returned by failingProfiler
+var errStart = errors.New("start failed")
+
+// failingProfiler is a test profiler whose Start always fails
+type failingProfiler struct {
+	TestProfiler
+}
+
+func (f *failingProfiler) Start(ctx context.Context) error {
+	return errStart
+}
+
+func TestPauseResume(t *testing.T) {
+	manager := NewProfilerManager(config.Config{ApplicationName: "test-app", InitialState: core.ProfilingEnabled})
+
+	var transitionErr *core.TransitionError
+	if err := manager.Pause(context.Background()); !errors.As(err, &transitionErr) {
+		t.Errorf("Pause before Start should return *core.TransitionError, got %v", err)
+	}
+
+	first := &TestProfiler{name: "first"}
+	second := &TestProfiler{name: "second"}
+	manager.AddProfiler(first)
+	manager.AddProfiler(second)
+
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	if err := manager.Pause(context.Background()); err != nil {
+		t.Fatalf("Pause returned error: %v", err)
+	}
+	if manager.IsRunning() || !manager.IsPaused() {
+		t.Error("Manager should be paused")
+	}
+	if !first.paused || !second.paused {
+		t.Error("Pause should pause every profiler")
+	}
+
+	// Profilers added while paused join on Resume
+	third := &TestProfiler{name: "third"}
+	manager.AddProfiler(third)
+
+	expected := map[string]core.State{"first": core.StatePaused, "second": core.StatePaused, "third": core.StateInitialized}
+	if states := manager.States(); !maps.Equal(states, expected) {
+		t.Errorf("Expected states %v, got %v", expected, states)
+	}
+
+	if err := manager.Resume(context.Background()); err != nil {
+		t.Fatalf("Resume returned error: %v", err)
+	}
+	if !manager.IsRunning() || first.paused || second.paused {
+		t.Error("Resume should resume every profiler")
+	}
+	for name, state := range manager.States() {
+		if state != core.StateRunning {
+			t.Errorf("Expected %s to be running, got %s", name, state)
+		}
+	}
+}
+
+func TestRestartAfterStop(t *testing.T) {
+	manager := NewProfilerManager(config.Config{ApplicationName: "test-app"})
+	profiler := &TestProfiler{name: "test"}
+	manager.AddProfiler(profiler)
+
+	for i := 0; i < 2; i++ {
+		if err := manager.Start(context.Background()); err != nil {
+			t.Fatalf("Start %d returned error: %v", i, err)
+		}
+		if !profiler.started {
+			t.Fatalf("Start %d should start the profiler", i)
+		}
+
+		if err := manager.Stop(context.Background()); err != nil {
+			t.Fatalf("Stop %d returned error: %v", i, err)
+		}
+		if profiler.started || manager.States()["test"] != core.StateStopped {
+			t.Fatalf("Stop %d should stop the profiler", i)
+		}
+	}
+}
+
+func TestFailedState(t *testing.T) {
+	manager := NewProfilerManager(config.Config{ApplicationName: "test-app"})
+	manager.AddProfiler(&failingProfiler{TestProfiler{name: "broken"}})
+
+	if err := manager.Start(context.Background()); err == nil {
+		t.Fatal("Start should report the failing profiler")
+	}
+	if state := manager.States()["broken"]; state != core.StateFailed {
+		t.Errorf("Expected failed state, got %s", state)
+	}
+}
+
+func TestOnStateChange(t *testing.T) {
+	manager := NewProfilerManager(config.Config{ApplicationName: "test-app"})
+
+	var transitions []string
+	manager.OnStateChange(func(name string, from, to core.State, err error) {
+		transitions = append(transitions, name+":"+string(from)+"->"+string(to))
+	})
+
+	manager.AddProfiler(&TestProfiler{name: "test"})
+	manager.AddProfiler(&failingProfiler{TestProfiler{name: "broken"}})
+	manager.Start(context.Background())
+
+	var broken []string
+	for _, transition := range transitions {
+		if strings.HasPrefix(transition, "broken:") {
+			broken = append(broken, transition)
+		}
+	}
+	expected := []string{"broken:initialized->starting", "broken:starting->failed"}
+	if !slices.Equal(broken, expected) {
+		t.Errorf("Expected transitions %v, got %v", expected, broken)
+	}
+	if manager.State() != core.StateFailed {
+		t.Errorf("Expected manager to be failed, got %s", manager.State())
+	}
+
+	// A failed manager can be stopped and restarted
+	if err := manager.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+	if manager.State() != core.StateStopped {
+		t.Errorf("Expected manager to be stopped, got %s", manager.State())
+	}
+}
+
+// errStop is returned by stopFailingProfiler
+var errStop = errors.New("stop failed")
+
+// stopFailingProfiler is a test profiler whose Stop always fails
+type stopFailingProfiler struct {
+	TestProfiler
+}
+
+func (f *stopFailingProfiler) Stop(ctx context.Context) error {
+	return errStop
+}
+
+// blockingProfiler is a test profiler whose Stop blocks until its context is done
+type blockingProfiler struct {
+	TestProfiler
+}
+
+func (b *blockingProfiler) Stop(ctx context.Context) error {
+	<-ctx.Done()
+	return &core.TimeoutError{Name: b.name, Operation: "stop", Err: ctx.Err()}
+}
+
+func TestFollowsProfilerStateMachine(t *testing.T) {
+	cfg := restartConfig(5)
+	cfg.RestartBackoff, cfg.RestartMaxBackoff = time.Hour, time.Hour
+	cfg.ProfileTypes = []core.ProfileType{core.ProfileGoroutines}
+	cfg.OutputDir = t.TempDir()
+	cfg.Backends = []config.BackendConfig{{Name: "archive", Type: core.PprofBackend}}
+
+	manager := NewProfilerManager(cfg)
+	var transitions []string
+	manager.OnStateChange(func(name string, from, to core.State, err error) {
+		transitions = append(transitions, name+":"+string(from)+"->"+string(to))
+	})
+	if err := manager.Init(context.Background()); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	defer manager.Stop(context.Background())
+
+	// A change the profiler makes by itself is reported under the backend's name
+	manager.mu.Lock()
+	manager.profilers["archive"].(core.StateReporter).StateMachine().Fail(errors.New("disk full"))
+	manager.mu.Unlock()
+
+	expected := []string{"archive:initialized->starting", "archive:starting->running", "archive:running->failed"}
+	if !slices.Equal(transitions, expected) {
+		t.Errorf("Expected transitions %v, got %v", expected, transitions)
+	}
+	if state := manager.States()["archive"]; state != core.StateFailed {
+		t.Errorf("Expected the failed state of the profiler, got %s", state)
+	}
+	if archive := manager.Status().Profilers["archive"]; archive.NextRestart.IsZero() {
+		t.Errorf("Expected a restart to be scheduled, got %+v", archive)
+	}
+}
+
+func TestInitRetry(t *testing.T) {
+	// The first Init fails to listen on a port already in use
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen returned error: %v", err)
+	}
+	cfg := config.Config{
+		ApplicationName: "test-app",
+		InitialState:    core.ProfilingEnabled,
+		ProfileTypes:    []core.ProfileType{core.ProfileGoroutines},
+		Backends: []config.BackendConfig{
+			{Name: "debug", Type: core.HTTPBackend, Config: config.Config{ServerAddress: busy.Addr().String()}},
+		},
+	}
+
+	manager := NewProfilerManager(cfg)
+	if err := manager.Init(context.Background()); err == nil {
+		t.Fatal("Init should fail while the port is in use")
+	}
+
+	busy.Close()
+	if err := manager.Init(context.Background()); err != nil {
+		t.Fatalf("Retried Init returned error: %v", err)
+	}
+	defer manager.Stop(context.Background())
+
+	manager.mu.Lock()
+	backends := slices.Clone(manager.backends)
+	manager.mu.Unlock()
+	if !slices.Equal(backends, []string{"debug"}) {
+		t.Errorf("Expected backends [debug], got %v", backends)
+	}
+}
+
+func TestStopHonoursTimeout(t *testing.T) {
+	manager := NewProfilerManager(config.Config{ApplicationName: "test-app", Timeout: 20 * time.Millisecond})
+	manager.AddProfiler(&blockingProfiler{TestProfiler{name: "slow"}})
+	manager.Start(context.Background())
+
+	begin := time.Now()
+	err := manager.Stop(context.Background())
+
+	var timeoutErr *core.TimeoutError
+	if !errors.As(err, &timeoutErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected *core.TimeoutError wrapping context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("Stop should give up after the configured timeout, took %s", elapsed)
+	}
+	if state := manager.States()["slow"]; state != core.StateFailed {
+		t.Errorf("Expected failed state, got %s", state)
+	}
+}
+
+func TestStopDefaultsTimeout(t *testing.T) {
+	// The blackholed server accepts connections and never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen returned error: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	defaultTimeout := config.DefaultConfig.Timeout
+	config.DefaultConfig.Timeout = 200 * time.Millisecond
+	defer func() { config.DefaultConfig.Timeout = defaultTimeout }()
+
+	manager := NewProfilerManager(config.Config{
+		ApplicationName: "test-app",
+		Backend:         core.PyroscopeBackend,
+		ServerAddress:   "http://" + listener.Addr().String(),
+		ProfileTypes:    []core.ProfileType{core.ProfileCPU},
+		InitialState:    core.ProfilingEnabled,
+	})
+	if err := manager.Init(context.Background()); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+
+	begin := time.Now()
+	err = manager.Stop(context.Background())
+
+	var timeoutErr *core.TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("Expected *core.TimeoutError without a configured Timeout, got %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 2*time.Second {
+		t.Errorf("Stop should give up after the default timeout, took %s", elapsed)
+	}
+}
+
+func TestStopHonoursCallerContext(t *testing.T) {
+	manager := NewProfilerManager(config.Config{ApplicationName: "test-app", Timeout: time.Minute})
+	manager.AddProfiler(&blockingProfiler{TestProfiler{name: "slow"}})
+	manager.Start(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := manager.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the caller deadline to cut Stop short, got %v", err)
+	}
+}
+
+func TestStartHonoursCancelledContext(t *testing.T) {
+	manager := NewProfilerManager(config.Config{ApplicationName: "test-app"})
+	profiler := &TestProfiler{name: "test"}
+	manager.AddProfiler(profiler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := manager.Start(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if profiler.started {
+		t.Error("Profiler should not be started with a cancelled context")
+	}
+}
+
+func TestStartRollsBack(t *testing.T) {
+	manager := NewProfilerManager(config.Config{ApplicationName: "test-app"})
+
+	first := &TestProfiler{name: "a-first"}
+	second := &TestProfiler{name: "b-second"}
+	manager.AddProfiler(first)
+	manager.AddProfiler(second)
+	manager.AddProfiler(&failingProfiler{TestProfiler{name: "c-broken"}})
+
+	err := manager.Start(context.Background())
+	if err == nil {
+		t.Fatal("Start should fail")
+	}
+
+	var managerErr *ManagerError
+	if !errors.As(err, &managerErr) || managerErr.Operation != "Start" {
+		t.Errorf("Expected *ManagerError for Start, got %v", err)
+	}
+	if first.started || second.started {
+		t.Error("Profilers started before the failure should be rolled back")
+	}
+	for _, name := range []string{"a-first", "b-second"} {
+		if state := manager.States()[name]; state != core.StateStopped {
+			t.Errorf("Expected %s to be stopped after rollback, got %s", name, state)
+		}
+	}
+}
+
+func TestStopAggregatesErrors(t *testing.T) {
+	manager := NewProfilerManager(config.Config{ApplicationName: "test-app"})
+	manager.AddProfiler(&stopFailingProfiler{TestProfiler{name: "first"}})
+	manager.AddProfiler(&stopFailingProfiler{TestProfiler{name: "second"}})
+	manager.AddProfiler(&TestProfiler{name: "third"})
+
+	err := manager.Stop(context.Background())
+	if !errors.Is(err, errStop) {
+		t.Fatalf("Expected errors.Is to find the backend error, got %v", err)
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != 2 {
+		t.Fatalf("Expected both failures to be reported, got %v", err)
+	}
+	for _, e := range joined.Unwrap() {
+		var managerErr *ManagerError
+		if !errors.As(e, &managerErr) || managerErr.Operation != "Stop" {
+			t.Errorf("Expected *ManagerError for Stop, got %v", e)
+		}
+	}
+}
+
+func TestInitReportsMemoryError(t *testing.T) {
+	manager := NewProfilerManager(config.Config{
+		ApplicationName: "test-app",
+		Backend:         core.PprofBackend,
+		ProfileTypes:    []core.ProfileType{core.ProfileGoroutines},
+		InitialState:    core.ProfilingEnabled,
+		OutputDir:       t.TempDir(),
+		MemoryLimitMB:   1,
+	})
+
+	var memoryErr *memory.MemoryError
+	if err := manager.Init(context.Background()); !errors.As(err, &memoryErr) {
+		t.Fatalf("Expected *memory.MemoryError, got %v", err)
+	}
+}
+
+func TestManagerErrorUnwrap(t *testing.T) {
+	cause := errors.New("cause")
+	err := &ManagerError{Operation: "Start", Message: "failed to start profiler test", Err: cause}
+
+	if !errors.Is(err, cause) {
+		t.Error("errors.Is should find the underlying error")
+	}
+	if err.Error() != "manager error (Start): failed to start profiler test: cause" {
+		t.Errorf("Unexpected message '%s'", err.Error())
+	}
+}
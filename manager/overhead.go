@@ -0,0 +1,193 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/wasilak/profilego/core"
+)
+
+const (
+	// defaultOverheadCheckInterval is used when OverheadCheckInterval is not set
+	defaultOverheadCheckInterval = 10 * time.Second
+	// overheadResumeRatio is the fraction of the budget the overhead has to drop below
+	// before the last reduction is lifted
+	overheadResumeRatio = 0.5
+)
+
+// Stats reports the estimated cost of profiling, e.g. for a metrics endpoint
+type Stats struct {
+	Profilers       map[string]core.OverheadStats // profilers accounting for their overhead
+	Total           core.OverheadStats
+	CPUPercent      float64            // estimated CPU overhead during the last check, in percent of one CPU
+	SamplingReduced bool               // sampling rates are reduced to stay within the budget
+	Suspended       []core.ProfileType // profile types suspended to stay within the budget
+}
+
+// overheadBudget measures the profiling overhead and reduces it while it exceeds OverheadBudgetPercent.
+// The first step reduces sampling rates, every further one suspends the next of the guardStages.
+type overheadBudget struct {
+	stopCh    chan struct{}
+	cpuTime   time.Duration // total CPU time at the last check
+	checkedAt time.Time
+	percent   float64 // CPU overhead measured by the last check
+	stage     int     // number of reductions applied
+}
+
+// Stats returns the overhead of every profiler accounting for it and the actions taken to
+// stay within the overhead budget
+func (pm *ProfilerManager) Stats() Stats {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	stats := Stats{
+		Profilers:       make(map[string]core.OverheadStats),
+		CPUPercent:      pm.overhead.percent,
+		SamplingReduced: pm.overhead.stage > 0,
+		Suspended:       pm.overheadSuspended(),
+	}
+	for name, profiler := range pm.profilers {
+		if reporter, ok := profiler.(core.OverheadReporter); ok {
+			stats.Profilers[name] = reporter.Overhead()
+			stats.Total = stats.Total.Add(stats.Profilers[name])
+		}
+	}
+	return stats
+}
+
+// startOverhead starts measuring the profiling overhead, replacing a running measurement
+// Must be called with pm.mu held
+func (pm *ProfilerManager) startOverhead() {
+	if pm.overhead.stopCh != nil {
+		close(pm.overhead.stopCh)
+	}
+
+	interval := pm.config.OverheadCheckInterval
+	if interval <= 0 {
+		interval = defaultOverheadCheckInterval
+	}
+
+	stopCh := make(chan struct{})
+	pm.overhead.stopCh = stopCh
+	pm.overhead.cpuTime, pm.overhead.checkedAt = pm.overheadCPUTime(), time.Now()
+	pm.overhead.percent = 0
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				pm.checkOverhead(stopCh)
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// stopOverhead stops measuring the profiling overhead and lifts every reduction
+// Must be called with pm.mu held
+func (pm *ProfilerManager) stopOverhead(ctx context.Context) error {
+	if pm.overhead.stopCh != nil {
+		close(pm.overhead.stopCh)
+		pm.overhead.stopCh = nil
+	}
+
+	var errs []error
+	for pm.overhead.stage > 0 {
+		pm.overhead.stage--
+		errs = append(errs, pm.applyOverheadStage(ctx, pm.overhead.stage, false))
+	}
+	return errors.Join(errs...)
+}
+
+// checkOverhead measures the CPU overhead since the last check, applies the next reduction while it
+// exceeds the budget and lifts the last one once it drops below overheadResumeRatio of the budget
+func (pm *ProfilerManager) checkOverhead(stopCh chan struct{}) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	// A check of a replaced measurement may still run
+	if pm.overhead.stopCh != stopCh {
+		return
+	}
+
+	now := time.Now()
+	cpuTime := pm.overheadCPUTime()
+	// Removed profilers take their CPU time with them
+	delta := max(cpuTime-pm.overhead.cpuTime, 0)
+	pm.overhead.percent = float64(delta) / float64(now.Sub(pm.overhead.checkedAt)) * 100
+	pm.overhead.cpuTime, pm.overhead.checkedAt = cpuTime, now
+
+	budget := pm.config.OverheadBudgetPercent
+	if budget <= 0 || !pm.state.Is(core.StateRunning) {
+		return
+	}
+
+	var stage int
+	var reduce bool
+	switch {
+	case pm.overhead.percent > budget && pm.overhead.stage <= len(guardStages):
+		stage, reduce = pm.overhead.stage, true
+		pm.overhead.stage++
+		slog.Warn("profilego - profiling overhead over budget, reducing it", "stage", stage+1, "cpu_percent", pm.overhead.percent, "budget_percent", budget)
+	case pm.overhead.percent < budget*overheadResumeRatio && pm.overhead.stage > 0:
+		pm.overhead.stage--
+		stage = pm.overhead.stage
+		slog.Info("profilego - profiling overhead back under budget, lifting reduction", "stage", stage+1, "cpu_percent", pm.overhead.percent, "budget_percent", budget)
+	default:
+		return
+	}
+
+	ctx, cancel := pm.withTimeout(context.Background())
+	defer cancel()
+
+	if err := pm.applyOverheadStage(ctx, stage, reduce); err != nil {
+		slog.Error("profilego - failed to apply overhead budget action", "stage", stage+1, "error", err)
+	}
+}
+
+// applyOverheadStage applies or lifts a reduction: stage 0 reduces sampling rates,
+// later stages suspend guardStages[stage-1]
+// Must be called with pm.mu held
+func (pm *ProfilerManager) applyOverheadStage(ctx context.Context, stage int, reduce bool) error {
+	if stage > 0 {
+		return pm.setSuspended(ctx, guardStages[stage-1], reduce)
+	}
+
+	var errs []error
+	for _, name := range pm.names() {
+		if reducer, ok := pm.profilers[name].(core.SamplingReducer); ok {
+			if err := reducer.ReduceSampling(ctx, reduce); err != nil {
+				errs = append(errs, &ManagerError{Operation: "OverheadBudget", Message: "failed to update sampling of " + name, Err: err})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// overheadCPUTime returns the CPU time spent by all profilers accounting for their overhead
+// Must be called with pm.mu held
+func (pm *ProfilerManager) overheadCPUTime() time.Duration {
+	var total time.Duration
+	for _, profiler := range pm.profilers {
+		if reporter, ok := profiler.(core.OverheadReporter); ok {
+			total += reporter.Overhead().CPUTime
+		}
+	}
+	return total
+}
+
+// overheadSuspended returns the profile types suspended to stay within the overhead budget
+// Must be called with pm.mu held
+func (pm *ProfilerManager) overheadSuspended() []core.ProfileType {
+	if pm.overhead.stage <= 1 {
+		return nil
+	}
+	return slices.Concat(guardStages[:pm.overhead.stage-1]...)
+}
@@ -0,0 +1,154 @@
+package manager
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+// costlyProfiler is a test profiler spending a configurable share of one CPU on profiling
+type costlyProfiler struct {
+	suspendingProfiler
+	costMu  sync.Mutex
+	percent float64
+	cpuTime time.Duration
+	last    time.Time
+	reduced bool
+}
+
+func newCostlyProfiler(name string, percent float64) *costlyProfiler {
+	return &costlyProfiler{
+		suspendingProfiler: suspendingProfiler{TestProfiler: TestProfiler{name: name}, suspended: make(map[core.ProfileType]bool)},
+		percent:            percent,
+		last:               time.Now(),
+	}
+}
+
+func (c *costlyProfiler) Overhead() core.OverheadStats {
+	c.costMu.Lock()
+	defer c.costMu.Unlock()
+	now := time.Now()
+	c.cpuTime += time.Duration(float64(now.Sub(c.last)) * c.percent / 100)
+	c.last = now
+	return core.OverheadStats{CPUTime: c.cpuTime, BytesWritten: 100}
+}
+
+func (c *costlyProfiler) ReduceSampling(ctx context.Context, reduced bool) error {
+	c.costMu.Lock()
+	defer c.costMu.Unlock()
+	c.reduced = reduced
+	return nil
+}
+
+// setPercent changes the share of one CPU the profiler spends from now on
+func (c *costlyProfiler) setPercent(percent float64) {
+	c.Overhead()
+	c.costMu.Lock()
+	defer c.costMu.Unlock()
+	c.percent = percent
+}
+
+// isReduced returns whether the profiler was asked to reduce sampling
+func (c *costlyProfiler) isReduced() bool {
+	c.costMu.Lock()
+	defer c.costMu.Unlock()
+	return c.reduced
+}
+
+// waitForStats polls the manager stats until done returns true or the test times out
+func waitForStats(t *testing.T, manager *ProfilerManager, done func(Stats) bool) Stats {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats := manager.Stats()
+		if done(stats) {
+			return stats
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for stats, last: %+v", stats)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOverheadBudget(t *testing.T) {
+	manager := NewProfilerManager(config.Config{
+		ApplicationName:       "test-app",
+		InitialState:          core.ProfilingEnabled,
+		OverheadBudgetPercent: 2,
+		OverheadCheckInterval: time.Millisecond,
+	})
+
+	profiler := newCostlyProfiler("test", 50)
+	manager.AddProfiler(profiler)
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	defer manager.Stop(context.Background())
+
+	// Over budget, sampling is reduced first, then the guard stages are suspended
+	stats := waitForStats(t, manager, func(s Stats) bool { return len(s.Suspended) == 5 })
+	if !stats.SamplingReduced || !profiler.isReduced() {
+		t.Error("Sampling should have been reduced")
+	}
+	if !profiler.isSuspended(core.ProfileCPU) || !profiler.isSuspended(core.ProfileBlockCount) {
+		t.Error("Profiler should have been asked to suspend the profile types")
+	}
+	if stats.CPUPercent < 2 {
+		t.Errorf("Expected the measured overhead to exceed the budget, got %.2f%%", stats.CPUPercent)
+	}
+	if suspended := manager.Status().Suspended; len(suspended) != 5 {
+		t.Errorf("Expected 5 suspended profile types in status, got %v", suspended)
+	}
+
+	// Under half the budget, the reductions are lifted in reverse order
+	profiler.setPercent(0)
+	waitForStats(t, manager, func(s Stats) bool { return !s.SamplingReduced })
+	if profiler.isSuspended(core.ProfileCPU) || profiler.isReduced() {
+		t.Error("Every reduction should have been lifted")
+	}
+}
+
+func TestOverheadBudgetKeepsMemoryGuardSuspensions(t *testing.T) {
+	manager := NewProfilerManager(config.Config{ApplicationName: "test-app"})
+	manager.guard.stage = 1
+	manager.overhead.stage = 2
+
+	profiler := newCostlyProfiler("test", 0)
+	manager.AddProfiler(profiler)
+	if !profiler.isSuspended(core.ProfileCPU) || !profiler.isReduced() {
+		t.Fatal("New profilers should inherit the suspensions and reduced sampling")
+	}
+
+	manager.mu.Lock()
+	err := manager.stopOverhead(context.Background())
+	manager.mu.Unlock()
+	if err != nil {
+		t.Fatalf("stopOverhead returned error: %v", err)
+	}
+
+	// CPU is still suspended by the memory guard
+	if !profiler.isSuspended(core.ProfileCPU) || profiler.isReduced() {
+		t.Error("Only the overhead budget reductions should have been lifted")
+	}
+}
+
+func TestStats(t *testing.T) {
+	manager := NewProfilerManager(config.Config{ApplicationName: "test-app"})
+	manager.AddProfiler(newCostlyProfiler("first", 0))
+	manager.AddProfiler(newCostlyProfiler("second", 0))
+	manager.AddProfiler(&TestProfiler{name: "silent"})
+
+	stats := manager.Stats()
+	if len(stats.Profilers) != 2 {
+		t.Errorf("Expected stats of the 2 profilers reporting overhead, got %v", stats.Profilers)
+	}
+	if stats.Total.BytesWritten != 200 {
+		t.Errorf("Expected 200 bytes written in total, got %d", stats.Total.BytesWritten)
+	}
+}
@@ -0,0 +1,275 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+	"github.com/wasilak/profilego/memory"
+)
+
+// ConfigLoader returns the configuration to apply, e.g. read from a file or the environment
+type ConfigLoader func() (config.Config, error)
+
+// FileLoader returns a ConfigLoader resolving the configuration with config.Load
+func FileLoader(path string, overrides config.Config) ConfigLoader {
+	return func() (config.Config, error) {
+		cfg, _, err := config.Load(path, overrides)
+		return cfg, err
+	}
+}
+
+// EnvLoader returns a ConfigLoader resolving the configuration with config.FromEnv
+func EnvLoader() ConfigLoader {
+	return config.FromEnv
+}
+
+// ApplyConfig replaces the configuration at runtime and reconciles the profilers with it.
+// Profilers created from the configuration are recreated only when a setting they depend on
+// changed, backends added to or removed from Backends are started or stopped, and toggling
+// InitialState starts or stops all profilers. OTelTracerProvider, AdditionalAttrs and TagPolicy
+// are kept from the current configuration when not set in cfg.
+func (pm *ProfilerManager) ApplyConfig(cfg config.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	old := pm.config
+	if cfg.OTelTracerProvider == nil {
+		cfg.OTelTracerProvider = old.OTelTracerProvider
+	}
+	if cfg.AdditionalAttrs == nil {
+		cfg.AdditionalAttrs = old.AdditionalAttrs
+	}
+	if cfg.TagPolicy == nil {
+		cfg.TagPolicy = old.TagPolicy
+	}
+	pm.config = cfg
+
+	// Nothing has been created yet, Init will pick up the new configuration
+	if len(pm.backends) == 0 {
+		return nil
+	}
+
+	wasEnabled := pm.active() && old.InitialState == core.ProfilingEnabled
+	enabled := pm.active() && cfg.InitialState == core.ProfilingEnabled
+
+	ctx, cancel := pm.withTimeout(context.Background())
+	defer cancel()
+
+	errs := []error{pm.reconcileBackends(ctx, old, cfg)}
+
+	if pm.active() && memoryConfigChanged(old, cfg) {
+		if cfg.MemoryLimitMB <= 0 && cfg.MemoryLimitPercent <= 0 {
+			errs = append(errs, pm.stopGuard(ctx))
+		} else {
+			pm.startGuard()
+		}
+	}
+
+	if pm.active() && overheadConfigChanged(old, cfg) {
+		errs = append(errs, pm.stopOverhead(ctx))
+		pm.startOverhead()
+	}
+
+	if enabled != wasEnabled {
+		// Toggling InitialState overrides a pause
+		if pm.state.Is(core.StatePaused) {
+			errs = append(errs, pm.state.Transition(core.StateRunning))
+		}
+		for _, name := range pm.names() {
+			var err error
+			if enabled {
+				err = pm.startProfiler(ctx, name)
+			} else {
+				err = pm.stopProfiler(ctx, name)
+			}
+			if err != nil {
+				errs = append(errs, &ManagerError{Operation: "ApplyConfig", Message: "failed to toggle profiler " + name, Err: err})
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// reconcileBackends recreates the backends whose configuration changed, creates the added
+// ones and removes those no longer configured. Every profiler going away is stopped before any
+// new one starts, so a backend taking over the CPU profile does not find it still running.
+func (pm *ProfilerManager) reconcileBackends(ctx context.Context, old, cfg config.Config) error {
+	previous := make(map[string]config.Config)
+	for _, b := range old.EffectiveBackends() {
+		previous[b.Name] = b.Config
+	}
+
+	var errs []error
+	var names []string
+	var changed []config.BackendConfig
+	for _, b := range cfg.EffectiveBackends() {
+		names = append(names, b.Name)
+
+		prev, existed := previous[b.Name]
+		delete(previous, b.Name)
+		if existed && !profilerConfigChanged(prev, b.Config) {
+			continue
+		}
+		changed = append(changed, b)
+	}
+
+	for name := range previous {
+		slog.Info("profilego - backend removed from configuration, stopping profiler", "profiler", name)
+		errs = append(errs, pm.removeBackend(ctx, name))
+	}
+
+	stopped := make(map[string]bool)
+	for _, b := range changed {
+		slog.Info("profilego - configuration changed, restarting profiler", "profiler", b.Name)
+		err := pm.removeBackend(ctx, b.Name)
+		stopped[b.Name] = err == nil
+		errs = append(errs, err)
+	}
+	for _, b := range changed {
+		if stopped[b.Name] {
+			errs = append(errs, pm.addBackend(ctx, b.Name, b.Config))
+		}
+	}
+
+	pm.backends = names
+	warnCPUBackends(cfg)
+	return errors.Join(errs...)
+}
+
+// addBackend creates a profiler from cfg, starting it if profiling is active
+func (pm *ProfilerManager) addBackend(ctx context.Context, name string, cfg config.Config) error {
+	profiler, err := pm.createProfiler(cfg)
+	if err != nil {
+		return err
+	}
+
+	pm.register(name, profiler)
+
+	if err := pm.activate(ctx, name); err != nil {
+		return &ManagerError{Operation: "ApplyConfig", Message: "failed to start profiler " + name, Err: err}
+	}
+	return nil
+}
+
+// removeBackend stops the named profiler and removes it from the manager
+func (pm *ProfilerManager) removeBackend(ctx context.Context, name string) error {
+	if _, ok := pm.profilers[name]; !ok {
+		return nil
+	}
+
+	if err := pm.stopProfiler(ctx, name); err != nil {
+		return &ManagerError{Operation: "ApplyConfig", Message: "failed to stop profiler " + name, Err: err}
+	}
+	delete(pm.profilers, name)
+	delete(pm.machines, name)
+	pm.forgetRestarts(name)
+	return nil
+}
+
+// Watch reloads the configuration with load every interval and applies it whenever it changes.
+// It blocks until ctx is cancelled; run it in its own goroutine. Errors are logged and the
+// previous configuration is kept.
+func (pm *ProfilerManager) Watch(ctx context.Context, interval time.Duration, load ConfigLoader) {
+	pm.WatchFunc(ctx, interval, load, pm.ApplyConfig)
+}
+
+// WatchFunc is like Watch but applies changed configurations with apply, letting callers
+// that wrap the manager keep their own state in sync
+func (pm *ProfilerManager) WatchFunc(ctx context.Context, interval time.Duration, load ConfigLoader, apply func(config.Config) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	pm.mu.RLock()
+	last := pm.config
+	pm.mu.RUnlock()
+
+	for {
+		select {
+		case <-ticker.C:
+			cfg, err := load()
+			if err != nil {
+				slog.ErrorContext(ctx, "profilego - failed to reload configuration", "error", err)
+				continue
+			}
+			if !configChanged(last, cfg) {
+				continue
+			}
+			if err := apply(cfg); err != nil {
+				slog.ErrorContext(ctx, "profilego - failed to apply configuration", "error", err)
+				continue
+			}
+			last = cfg
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// configChanged reports whether two configurations differ in any loadable field
+func configChanged(a, b config.Config) bool {
+	return !reflect.DeepEqual(normalized(a), normalized(b))
+}
+
+// normalized drops fields that cannot be loaded and treats empty collections like nil ones
+func normalized(c config.Config) config.Config {
+	c.OTelTracerProvider = nil
+	c.AdditionalAttrs = nil
+	c.TagPolicy = nil
+	if len(c.Tags) == 0 {
+		c.Tags = nil
+	}
+	if len(c.ProfileTypes) == 0 {
+		c.ProfileTypes = nil
+	}
+	if len(c.HTTPHeaders) == 0 {
+		c.HTTPHeaders = nil
+	}
+	if len(c.Backends) == 0 {
+		c.Backends = nil
+	}
+	c.Backends = slices.Clone(c.Backends)
+	for i := range c.Backends {
+		c.Backends[i].Config = normalized(c.Backends[i].Config)
+	}
+	return c
+}
+
+// memoryConfigChanged reports whether two configurations differ in a memory guard setting
+func memoryConfigChanged(a, b config.Config) bool {
+	return memory.FromConfig(a) != memory.FromConfig(b) || a.MemoryCheckInterval != b.MemoryCheckInterval
+}
+
+// overheadConfigChanged reports whether two configurations differ in an overhead budget setting
+func overheadConfigChanged(a, b config.Config) bool {
+	return a.OverheadBudgetPercent != b.OverheadBudgetPercent || a.OverheadCheckInterval != b.OverheadCheckInterval
+}
+
+// profilerConfigChanged reports whether two configurations differ in a field a profiler depends on.
+// InitialState is handled by starting or stopping profilers instead, and the other lifecycle,
+// memory guard, overhead budget and restart settings are only used by the manager.
+func profilerConfigChanged(a, b config.Config) bool {
+	return configChanged(profilerFields(a), profilerFields(b))
+}
+
+// profilerFields clears the fields of a configuration only the manager uses
+func profilerFields(c config.Config) config.Config {
+	c.InitialState = ""
+	c.Timeout = 0
+	c.LogLevel = ""
+	c.MemoryCheckInterval, c.MemoryResumeRatio = 0, 0
+	c.OverheadBudgetPercent, c.OverheadCheckInterval = 0, 0
+	c.RestartMaxAttempts, c.RestartBackoff, c.RestartMaxBackoff = 0, 0, 0
+	c.AllowDegraded = false
+	return c
+}
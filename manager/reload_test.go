@@ -0,0 +1,240 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+// newReloadTestManager returns an initialized manager with a pprof backend and a test profiler
+func newReloadTestManager(t *testing.T, state core.ProfilingState) (*ProfilerManager, *TestProfiler) {
+	t.Helper()
+
+	cfg := config.Config{
+		ApplicationName: "test-app",
+		Backend:         core.PprofBackend,
+		ProfileTypes:    []core.ProfileType{core.ProfileGoroutines},
+		InitialState:    state,
+		OutputDir:       t.TempDir(),
+		MemoryLimitMB:   1024,
+	}
+
+	manager := NewProfilerManager(cfg)
+	if err := manager.Init(context.Background()); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+
+	extra := &TestProfiler{name: "extra", started: state == core.ProfilingEnabled}
+	if err := manager.AddProfiler(extra); err != nil {
+		t.Fatalf("AddProfiler returned error: %v", err)
+	}
+
+	t.Cleanup(func() { manager.Stop(context.Background()) })
+	return manager, extra
+}
+
+func TestApplyConfigRestartsChangedBackend(t *testing.T) {
+	manager, extra := newReloadTestManager(t, core.ProfilingEnabled)
+	before := manager.profilers["pprof"]
+
+	cfg := manager.config
+	cfg.Tags = map[string]string{"incident": "1234"}
+	cfg.ProfileTypes = []core.ProfileType{core.ProfileGoroutines, core.ProfileMutexCount}
+	if err := manager.ApplyConfig(cfg); err != nil {
+		t.Fatalf("ApplyConfig returned error: %v", err)
+	}
+
+	after := manager.profilers["pprof"]
+	if after == before {
+		t.Error("Expected pprof profiler to be recreated after config change")
+	}
+	if !after.IsRunning() {
+		t.Error("Expected recreated profiler to be running")
+	}
+	if before.IsRunning() {
+		t.Error("Expected previous profiler to be stopped")
+	}
+	if !extra.started {
+		t.Error("Unaffected profiler should keep running")
+	}
+}
+
+func TestApplyConfigKeepsUnchangedBackend(t *testing.T) {
+	manager, _ := newReloadTestManager(t, core.ProfilingEnabled)
+	before := manager.profilers["pprof"]
+
+	if err := manager.ApplyConfig(manager.config); err != nil {
+		t.Fatalf("ApplyConfig returned error: %v", err)
+	}
+
+	if manager.profilers["pprof"] != before {
+		t.Error("Profiler should not be recreated when configuration is unchanged")
+	}
+}
+
+func TestApplyConfigKeepsBackendOnManagerSettings(t *testing.T) {
+	manager, _ := newReloadTestManager(t, core.ProfilingEnabled)
+	before := manager.profilers["pprof"]
+
+	cfg := manager.config
+	cfg.Timeout = 3 * time.Second
+	cfg.LogLevel = "debug"
+	cfg.MemoryCheckInterval = time.Minute
+	cfg.MemoryResumeRatio = 0.5
+	cfg.OverheadBudgetPercent = 5
+	cfg.OverheadCheckInterval = time.Minute
+	cfg.RestartMaxAttempts = 3
+	cfg.RestartBackoff = time.Second
+	cfg.RestartMaxBackoff = time.Minute
+	cfg.AllowDegraded = true
+	if err := manager.ApplyConfig(cfg); err != nil {
+		t.Fatalf("ApplyConfig returned error: %v", err)
+	}
+
+	if manager.profilers["pprof"] != before {
+		t.Error("Profiler should not be recreated when only manager settings changed")
+	}
+	if manager.config.Timeout != 3*time.Second {
+		t.Errorf("Expected the new timeout to be applied, got %v", manager.config.Timeout)
+	}
+}
+
+func TestApplyConfigTogglesInitialState(t *testing.T) {
+	manager, extra := newReloadTestManager(t, core.ProfilingDisabled)
+	backend := manager.profilers["pprof"]
+
+	cfg := manager.config
+	cfg.InitialState = core.ProfilingEnabled
+	if err := manager.ApplyConfig(cfg); err != nil {
+		t.Fatalf("ApplyConfig returned error: %v", err)
+	}
+
+	if manager.profilers["pprof"] != backend {
+		t.Error("Toggling InitialState should not recreate the profiler")
+	}
+	if !backend.IsRunning() || !extra.started {
+		t.Error("Enabling profiling should start all profilers")
+	}
+
+	cfg.InitialState = core.ProfilingDisabled
+	if err := manager.ApplyConfig(cfg); err != nil {
+		t.Fatalf("ApplyConfig returned error: %v", err)
+	}
+
+	if backend.IsRunning() || extra.started {
+		t.Error("Disabling profiling should stop all profilers")
+	}
+}
+
+func TestApplyConfigRejectsInvalidConfig(t *testing.T) {
+	manager, _ := newReloadTestManager(t, core.ProfilingEnabled)
+
+	cfg := manager.config
+	cfg.ProfileTypes = []core.ProfileType{"threads"}
+
+	var validationErr *config.ValidationError
+	if err := manager.ApplyConfig(cfg); !errors.As(err, &validationErr) {
+		t.Fatalf("Expected *config.ValidationError, got %v", err)
+	}
+	if manager.config.ProfileTypes[0] != core.ProfileGoroutines {
+		t.Error("Configuration should be unchanged after invalid ApplyConfig")
+	}
+}
+
+func TestWatchAppliesLoadedConfig(t *testing.T) {
+	manager, _ := newReloadTestManager(t, core.ProfilingEnabled)
+
+	loaded := manager.config
+	loaded.Tags = map[string]string{"role": "leader"}
+	load := func() (config.Config, error) {
+		return loaded, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go manager.Watch(ctx, 10*time.Millisecond, load)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		manager.mu.RLock()
+		role := manager.config.Tags["role"]
+		manager.mu.RUnlock()
+		if role == "leader" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Watch did not apply the loaded configuration")
+}
+
+// freeAddr returns a local address that is free to listen on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func TestInitCreatesEveryBackend(t *testing.T) {
+	addr := freeAddr(t)
+	cfg := config.Config{
+		ApplicationName: "test-app",
+		ProfileTypes:    []core.ProfileType{core.ProfileGoroutines},
+		InitialState:    core.ProfilingEnabled,
+		OutputDir:       t.TempDir(),
+		Backends: []config.BackendConfig{
+			{Type: core.PprofBackend},
+			{Name: "debug", Type: core.HTTPBackend, Config: config.Config{ServerAddress: addr}},
+		},
+	}
+
+	manager := NewProfilerManager(cfg)
+	if err := manager.Init(context.Background()); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	defer manager.Stop(context.Background())
+
+	for _, name := range []string{"pprof", "debug"} {
+		profiler, ok := manager.profilers[name]
+		if !ok {
+			t.Fatalf("Expected profiler %s, got %v", name, manager.profilers)
+		}
+		if !profiler.IsRunning() {
+			t.Errorf("Expected profiler %s to be running", name)
+		}
+	}
+
+	// Removing a backend stops it and keeps the others untouched
+	pprof := manager.profilers["pprof"]
+	debug := manager.profilers["debug"]
+	cfg.Backends = slices.Clone(cfg.Backends[:1])
+	if err := manager.ApplyConfig(cfg); err != nil {
+		t.Fatalf("ApplyConfig returned error: %v", err)
+	}
+
+	if _, ok := manager.profilers["debug"]; ok || debug.IsRunning() {
+		t.Error("Removed backend should be stopped and dropped")
+	}
+	if manager.profilers["pprof"] != pprof {
+		t.Error("Unchanged backend should not be recreated")
+	}
+
+	// Adding it back creates and starts a new profiler
+	cfg.Backends = append(cfg.Backends, config.BackendConfig{Name: "debug", Type: core.HTTPBackend, Config: config.Config{ServerAddress: addr}})
+	if err := manager.ApplyConfig(cfg); err != nil {
+		t.Fatalf("ApplyConfig returned error: %v", err)
+	}
+	if profiler, ok := manager.profilers["debug"]; !ok || !profiler.IsRunning() {
+		t.Error("Added backend should be created and started")
+	}
+}
@@ -0,0 +1,228 @@
+package manager
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/wasilak/profilego/core"
+)
+
+const (
+	// defaultRestartMaxAttempts is used when RestartMaxAttempts is not set
+	defaultRestartMaxAttempts = 5
+	// defaultRestartBackoff is used when RestartBackoff is not set
+	defaultRestartBackoff = time.Second
+	// defaultRestartMaxBackoff is used when RestartMaxBackoff is not set
+	defaultRestartMaxBackoff = time.Minute
+)
+
+// Status is a snapshot of the manager and its profilers, e.g. for a health endpoint
+type Status struct {
+	State     core.State
+	Degraded  bool               // some profilers have failed while the manager is running
+	Suspended []core.ProfileType // profile types suspended by the memory guard or the overhead budget
+	Profilers map[string]ProfilerStatus
+}
+
+// ProfilerStatus describes a managed profiler and its automatic restarts
+type ProfilerStatus struct {
+	State       core.State
+	Attempts    int       // restart attempts since the profiler last failed
+	Restarts    int       // successful automatic restarts
+	LastError   error     // error of the last failure while the profiler is failed
+	NextRestart time.Time // when the next restart is due, zero if none is scheduled
+}
+
+// restart tracks the automatic restarts of a profiler
+type restart struct {
+	attempts int
+	restarts int
+	lastErr  error
+	next     time.Time
+	timer    *time.Timer
+	seq      int  // invalidates timers that were cancelled but already fired
+	deferred bool // a restart fell due while the manager was paused
+}
+
+// supervise is notified of every profiler state change and schedules a restart with
+// exponential backoff when a profiler fails to start or fails while running
+// Called with pm.mu held by the operation causing the change
+func (pm *ProfilerManager) supervise(name string, from, to core.State, err error) {
+	r := pm.restarts[name]
+	if r == nil {
+		r = &restart{}
+		pm.restarts[name] = r
+	}
+
+	switch {
+	case to == core.StateRunning:
+		if r.attempts > 0 {
+			r.restarts++
+			slog.Info("profilego - profiler restarted", "profiler", name, "attempts", r.attempts)
+		}
+		r.attempts, r.lastErr = 0, nil
+	case to == core.StateFailed:
+		r.lastErr = err
+		// A failed transactional start is rolled back and reported instead
+		if from == core.StateStarting && (!pm.state.Is(core.StateStarting) || pm.config.AllowDegraded) {
+			pm.scheduleRestart(name, r)
+		}
+		// So is a profiler that stopped while running, e.g. when restarting to change its profile types
+		if from == core.StateRunning {
+			pm.scheduleRestart(name, r)
+		}
+	}
+}
+
+// failIfStopped marks a profiler that stopped running after a failed operation as failed,
+// so that it is reported and restarted
+// Must be called with pm.mu held
+func (pm *ProfilerManager) failIfStopped(name string, err error) {
+	if machine := pm.machines[name]; machine.Is(core.StateRunning) && !pm.profilers[name].IsRunning() {
+		machine.Fail(err)
+	}
+}
+
+// scheduleRestart restarts the named profiler after a backoff unless it ran out of attempts
+// or restarts are disabled with a negative RestartMaxAttempts
+// Must be called with pm.mu held
+func (pm *ProfilerManager) scheduleRestart(name string, r *restart) {
+	maxAttempts := pm.config.RestartMaxAttempts
+	if maxAttempts < 0 {
+		return
+	}
+	if maxAttempts == 0 {
+		maxAttempts = defaultRestartMaxAttempts
+	}
+	if r.attempts >= maxAttempts {
+		slog.Error("profilego - giving up restarting profiler", "profiler", name, "attempts", r.attempts, "error", r.lastErr)
+		return
+	}
+
+	delay := backoff(pm.config.RestartBackoff, pm.config.RestartMaxBackoff, r.attempts)
+	slog.Warn("profilego - profiler failed, scheduling restart", "profiler", name, "attempt", r.attempts+1, "delay", delay, "error", r.lastErr)
+
+	r.stop()
+	seq := r.seq
+	r.next = time.Now().Add(delay)
+	r.timer = time.AfterFunc(delay, func() {
+		pm.restart(name, seq)
+	})
+}
+
+// restart makes a scheduled restart attempt. A failure schedules the next one through supervise.
+func (pm *ProfilerManager) restart(name string, seq int) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	r := pm.restarts[name]
+	if r == nil || r.seq != seq {
+		return
+	}
+	r.timer, r.next = nil, time.Time{}
+
+	if machine := pm.machines[name]; machine == nil || !machine.Is(core.StateFailed) {
+		return
+	}
+	if pm.state.Is(core.StatePaused) {
+		r.deferred = true
+		return
+	}
+	if !pm.state.Is(core.StateRunning) {
+		return
+	}
+
+	ctx, cancel := pm.withTimeout(context.Background())
+	defer cancel()
+
+	r.attempts++
+	pm.startProfiler(ctx, name)
+}
+
+// retryDeferred makes the restart attempt of a profiler whose restart fell due while the
+// manager was paused, reporting whether there was one
+// Must be called with pm.mu held
+func (pm *ProfilerManager) retryDeferred(ctx context.Context, name string) (bool, error) {
+	r := pm.restarts[name]
+	if r == nil || !r.deferred {
+		return false, nil
+	}
+
+	r.deferred = false
+	r.attempts++
+	return true, pm.startProfiler(ctx, name)
+}
+
+// cancelRestarts cancels all pending restarts and resets the attempt counts
+// Must be called with pm.mu held
+func (pm *ProfilerManager) cancelRestarts() {
+	for _, r := range pm.restarts {
+		r.stop()
+		r.attempts = 0
+	}
+}
+
+// forgetRestarts cancels the pending restart of a removed or replaced profiler and drops its counts
+// Must be called with pm.mu held
+func (pm *ProfilerManager) forgetRestarts(name string) {
+	if r := pm.restarts[name]; r != nil {
+		r.stop()
+		delete(pm.restarts, name)
+	}
+}
+
+// stop cancels the pending restart, if any
+func (r *restart) stop() {
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	r.seq++
+	r.timer, r.next, r.deferred = nil, time.Time{}, false
+}
+
+// Status returns a snapshot of the manager and its profilers, including restart attempts
+func (pm *ProfilerManager) Status() Status {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	status := Status{
+		State:     pm.state.State(),
+		Suspended: pm.suspendedTypes(),
+		Profilers: make(map[string]ProfilerStatus, len(pm.machines)),
+	}
+	for name, machine := range pm.machines {
+		s := ProfilerStatus{State: machine.State()}
+		if r := pm.restarts[name]; r != nil {
+			s.Attempts, s.Restarts, s.NextRestart = r.attempts, r.restarts, r.next
+			if s.State == core.StateFailed {
+				s.LastError = r.lastErr
+			}
+		}
+		if s.State == core.StateFailed && status.State == core.StateRunning {
+			status.Degraded = true
+		}
+		status.Profilers[name] = s
+	}
+	return status
+}
+
+// backoff returns the delay before a restart attempt: base doubled for every previous attempt,
+// capped at maxDelay, with up to half of it replaced by random jitter
+func backoff(base, maxDelay time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		base = defaultRestartBackoff
+	}
+	if maxDelay <= 0 {
+		maxDelay = max(defaultRestartMaxBackoff, base)
+	}
+
+	delay := base
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxDelay)
+
+	return delay/2 + rand.N(delay/2+1)
+}
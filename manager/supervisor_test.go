@@ -0,0 +1,231 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+// flakyProfiler is a test profiler whose Start fails a given number of times before succeeding
+type flakyProfiler struct {
+	TestProfiler
+	failures int
+}
+
+func (f *flakyProfiler) Start(ctx context.Context) error {
+	if f.failures != 0 {
+		f.failures--
+		return errStart
+	}
+	return f.TestProfiler.Start(ctx)
+}
+
+// restartConfig returns a configuration restarting failed profilers quickly
+func restartConfig(attempts int) config.Config {
+	return config.Config{
+		ApplicationName:    "test-app",
+		InitialState:       core.ProfilingEnabled,
+		AllowDegraded:      true,
+		RestartMaxAttempts: attempts,
+		RestartBackoff:     time.Millisecond,
+		RestartMaxBackoff:  5 * time.Millisecond,
+	}
+}
+
+// waitForStatus polls the manager status until done returns true or the test times out
+func waitForStatus(t *testing.T, manager *ProfilerManager, done func(Status) bool) Status {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := manager.Status()
+		if done(status) {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for status, last: %+v", status)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSupervisorRestartsFailedProfiler(t *testing.T) {
+	manager := NewProfilerManager(restartConfig(5))
+	manager.AddProfiler(&flakyProfiler{TestProfiler: TestProfiler{name: "flaky"}, failures: 2})
+	manager.AddProfiler(&TestProfiler{name: "healthy"})
+	defer manager.Stop(context.Background())
+
+	// Degraded mode lets Start succeed with the healthy profiler running
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	status := manager.Status()
+	if status.State != core.StateRunning || status.Profilers["healthy"].State != core.StateRunning {
+		t.Fatalf("Expected the manager and the healthy profiler to run, got %+v", status)
+	}
+
+	status = waitForStatus(t, manager, func(s Status) bool {
+		return s.Profilers["flaky"].State == core.StateRunning
+	})
+	if flaky := status.Profilers["flaky"]; flaky.Restarts != 1 || flaky.Attempts != 0 || flaky.LastError != nil {
+		t.Errorf("Expected one successful restart, got %+v", flaky)
+	}
+	if status.Degraded {
+		t.Error("Manager should no longer be degraded")
+	}
+}
+
+func TestSupervisorGivesUp(t *testing.T) {
+	manager := NewProfilerManager(restartConfig(2))
+	manager.AddProfiler(&failingProfiler{TestProfiler{name: "broken"}})
+	defer manager.Stop(context.Background())
+
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	status := waitForStatus(t, manager, func(s Status) bool {
+		broken := s.Profilers["broken"]
+		return broken.Attempts == 2 && broken.NextRestart.IsZero()
+	})
+	if !status.Degraded {
+		t.Error("Manager should be degraded")
+	}
+	if broken := status.Profilers["broken"]; broken.State != core.StateFailed || !errors.Is(broken.LastError, errStart) {
+		t.Errorf("Expected the profiler to stay failed with its error, got %+v", broken)
+	}
+}
+
+func TestSupervisorDefaultAttempts(t *testing.T) {
+	manager := NewProfilerManager(restartConfig(0))
+	manager.AddProfiler(&failingProfiler{TestProfiler{name: "broken"}})
+	defer manager.Stop(context.Background())
+
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	waitForStatus(t, manager, func(s Status) bool {
+		broken := s.Profilers["broken"]
+		return broken.Attempts == defaultRestartMaxAttempts && broken.NextRestart.IsZero()
+	})
+}
+
+func TestSupervisorRestartsDisabled(t *testing.T) {
+	manager := NewProfilerManager(restartConfig(-1))
+	manager.AddProfiler(&failingProfiler{TestProfiler{name: "broken"}})
+	defer manager.Stop(context.Background())
+
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if broken := manager.Status().Profilers["broken"]; !broken.NextRestart.IsZero() || broken.Attempts != 0 {
+		t.Errorf("No restart should be scheduled with a negative RestartMaxAttempts, got %+v", broken)
+	}
+}
+
+func TestSupervisorRestartsProfilerFailedBySuspension(t *testing.T) {
+	// The blackholed server never answers, so restarting Pyroscope times out flushing its profiles
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen returned error: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	cfg := restartConfig(5)
+	cfg.Backend, cfg.ServerAddress = core.PyroscopeBackend, "http://"+listener.Addr().String()
+	cfg.ProfileTypes = []core.ProfileType{core.ProfileCPU, core.ProfileGoroutines}
+	cfg.Timeout = 200 * time.Millisecond
+	cfg.RestartBackoff, cfg.RestartMaxBackoff = time.Hour, time.Hour
+
+	manager := NewProfilerManager(cfg)
+	if err := manager.Init(context.Background()); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	defer manager.Stop(context.Background())
+
+	manager.mu.Lock()
+	ctx, cancel := manager.withTimeout(context.Background())
+	err = manager.setSuspended(ctx, []core.ProfileType{core.ProfileGoroutines}, true)
+	cancel()
+	manager.mu.Unlock()
+	if err == nil {
+		t.Fatal("Expected the restart of the Pyroscope profiler to fail")
+	}
+
+	status := manager.Status()
+	if pyroscope := status.Profilers["pyroscope"]; pyroscope.State != core.StateFailed || pyroscope.NextRestart.IsZero() {
+		t.Errorf("Expected the profiler to be failed with a restart scheduled, got %+v", pyroscope)
+	}
+	if !status.Degraded {
+		t.Error("Manager should be degraded")
+	}
+}
+
+func TestSupervisorSkipsTransactionalStart(t *testing.T) {
+	cfg := restartConfig(5)
+	cfg.AllowDegraded = false
+
+	manager := NewProfilerManager(cfg)
+	manager.AddProfiler(&failingProfiler{TestProfiler{name: "broken"}})
+
+	if err := manager.Start(context.Background()); err == nil {
+		t.Fatal("Start should fail without AllowDegraded")
+	}
+	if broken := manager.Status().Profilers["broken"]; !broken.NextRestart.IsZero() {
+		t.Errorf("No restart should be scheduled after a failed transactional start, got %+v", broken)
+	}
+}
+
+func TestStopCancelsRestarts(t *testing.T) {
+	cfg := restartConfig(5)
+	cfg.RestartBackoff, cfg.RestartMaxBackoff = time.Hour, time.Hour
+
+	manager := NewProfilerManager(cfg)
+	manager.AddProfiler(&failingProfiler{TestProfiler{name: "broken"}})
+	manager.Start(context.Background())
+
+	if manager.Status().Profilers["broken"].NextRestart.IsZero() {
+		t.Fatal("A restart should be scheduled")
+	}
+	if err := manager.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+	if broken := manager.Status().Profilers["broken"]; !broken.NextRestart.IsZero() || broken.Attempts != 0 {
+		t.Errorf("Stop should cancel pending restarts, got %+v", broken)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	testCases := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{1, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond, 800 * time.Millisecond},
+		{10, 500 * time.Millisecond, time.Second},
+	}
+	for _, tc := range testCases {
+		for i := 0; i < 20; i++ {
+			delay := backoff(100*time.Millisecond, time.Second, tc.attempt)
+			if delay < tc.min || delay > tc.max {
+				t.Errorf("attempt %d: expected delay in [%s, %s], got %s", tc.attempt, tc.min, tc.max, delay)
+			}
+		}
+	}
+}
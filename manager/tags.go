@@ -0,0 +1,85 @@
+package manager
+
+import (
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+// globalTags are the static tags set or removed at runtime, applied on top of the configured Tags
+type globalTags struct {
+	set     map[string]string
+	removed map[string]bool
+}
+
+// SetGlobalTag adds or replaces a static tag on every profiler supporting it, without restarting them.
+// The tag is also applied to profilers created later, e.g. when the configuration is reloaded.
+func (pm *ProfilerManager) SetGlobalTag(key, value string) error {
+	if err := config.ValidateTagKey(key); err != nil {
+		return err
+	}
+	if err := config.ValidateTagValue(value); err != nil {
+		return err
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if pm.tags.set == nil {
+		pm.tags.set = make(map[string]string)
+	}
+	pm.tags.set[key] = value
+	delete(pm.tags.removed, key)
+
+	var errs []error
+	for _, name := range pm.names() {
+		if tagger, ok := pm.profilers[name].(core.GlobalTagger); ok {
+			if err := tagger.SetGlobalTag(key, value); err != nil {
+				errs = append(errs, &ManagerError{Operation: "SetGlobalTag", Message: "failed to set tag on " + name, Err: err})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RemoveGlobalTag removes a static tag, configured or set at runtime, from every profiler supporting it.
+// The tag stays removed for profilers created later.
+func (pm *ProfilerManager) RemoveGlobalTag(key string) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if pm.tags.removed == nil {
+		pm.tags.removed = make(map[string]bool)
+	}
+	pm.tags.removed[key] = true
+	delete(pm.tags.set, key)
+
+	var errs []error
+	for _, name := range pm.names() {
+		if tagger, ok := pm.profilers[name].(core.GlobalTagger); ok {
+			if err := tagger.RemoveGlobalTag(key); err != nil {
+				errs = append(errs, &ManagerError{Operation: "RemoveGlobalTag", Message: "failed to remove tag from " + name, Err: err})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// applyGlobalTags applies the tags set or removed at runtime to a new profiler
+// Must be called with pm.mu held
+func (pm *ProfilerManager) applyGlobalTags(name string, tagger core.GlobalTagger) {
+	for _, key := range slices.Sorted(maps.Keys(pm.tags.removed)) {
+		if err := tagger.RemoveGlobalTag(key); err != nil {
+			slog.Error("profilego - failed to remove global tag", "profiler", name, "key", key, "error", err)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(pm.tags.set)) {
+		if err := tagger.SetGlobalTag(key, pm.tags.set[key]); err != nil {
+			slog.Error("profilego - failed to set global tag", "profiler", name, "key", key, "error", err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package manager
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+// taggedProfiler is a test profiler recording its static tags
+type taggedProfiler struct {
+	TestProfiler
+	tags map[string]string
+}
+
+func (p *taggedProfiler) SetGlobalTag(key, value string) error {
+	p.tags[key] = value
+	return nil
+}
+
+func (p *taggedProfiler) RemoveGlobalTag(key string) error {
+	delete(p.tags, key)
+	return nil
+}
+
+func TestGlobalTags(t *testing.T) {
+	manager := NewProfilerManager(config.Config{
+		ApplicationName: "test-app",
+		InitialState:    core.ProfilingEnabled,
+	})
+
+	first := &taggedProfiler{TestProfiler: TestProfiler{name: "first"}, tags: map[string]string{"env": "prod", "role": "leader"}}
+	if err := manager.AddProfiler(first); err != nil {
+		t.Fatalf("AddProfiler returned error: %v", err)
+	}
+
+	if err := manager.SetGlobalTag("canary", "true"); err != nil {
+		t.Fatalf("SetGlobalTag returned error: %v", err)
+	}
+	if err := manager.RemoveGlobalTag("role"); err != nil {
+		t.Fatalf("RemoveGlobalTag returned error: %v", err)
+	}
+
+	expected := map[string]string{"env": "prod", "canary": "true"}
+	if !maps.Equal(first.tags, expected) {
+		t.Errorf("Expected tags %v, got %v", expected, first.tags)
+	}
+
+	// Profilers added later get the tags changed at runtime too
+	second := &taggedProfiler{TestProfiler: TestProfiler{name: "second"}, tags: map[string]string{"env": "prod", "role": "leader"}}
+	if err := manager.AddProfiler(second); err != nil {
+		t.Fatalf("AddProfiler returned error: %v", err)
+	}
+	if !maps.Equal(second.tags, expected) {
+		t.Errorf("Expected tags %v for a new profiler, got %v", expected, second.tags)
+	}
+
+	var configErr *config.ConfigError
+	if err := manager.SetGlobalTag("feature-flags", "a"); !errors.As(err, &configErr) {
+		t.Errorf("Expected *config.ConfigError for an invalid tag key, got %v", err)
+	}
+	if err := manager.SetGlobalTag("__session_id__", "a"); err == nil {
+		t.Error("Expected an error for a reserved tag key")
+	}
+	if err := manager.SetGlobalTag("team", "a,b"); !errors.As(err, &configErr) {
+		t.Errorf("Expected *config.ConfigError for a tag value with a comma, got %v", err)
+	}
+}
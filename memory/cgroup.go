@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultCgroupRoot is where the cgroup filesystem is usually mounted
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+// cgroupV1Unlimited is the smallest value cgroup v1 reports for an unlimited memory limit;
+// the exact value is the largest page-aligned int64
+const cgroupV1Unlimited = 1 << 62
+
+var (
+	// procSelfCgroup lists the cgroups of the current process
+	procSelfCgroup = "/proc/self/cgroup"
+	// procSelfStatus holds the memory statistics of the current process
+	procSelfStatus = "/proc/self/status"
+)
+
+// ErrNoCgroupLimit is returned when the cgroup sets no memory limit
+var ErrNoCgroupLimit = errors.New("no cgroup memory limit set")
+
+// CgroupLimit returns the memory limit in bytes of the cgroup mounted at root, which can be
+// cgroup v2 (memory.max) or v1 (memory/memory.limit_in_bytes). It returns ErrNoCgroupLimit
+// if the memory is unlimited.
+func CgroupLimit(root string) (uint64, error) {
+	v2, v1 := "memory.max", filepath.Join("memory", "memory.limit_in_bytes")
+	raw, err := readCgroupFile(root, v2, v1)
+	if err != nil {
+		return 0, err
+	}
+
+	if raw == "max" {
+		return 0, ErrNoCgroupLimit
+	}
+	limit, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cgroup memory limit %q: %w", raw, err)
+	}
+	if limit >= cgroupV1Unlimited {
+		return 0, ErrNoCgroupLimit
+	}
+	return limit, nil
+}
+
+// CgroupUsage returns the memory usage in bytes of the cgroup mounted at root, read from
+// memory.current (v2) or memory/memory.usage_in_bytes (v1)
+func CgroupUsage(root string) (uint64, error) {
+	raw, err := readCgroupFile(root, "memory.current", filepath.Join("memory", "memory.usage_in_bytes"))
+	if err != nil {
+		return 0, err
+	}
+
+	usage, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cgroup memory usage %q: %w", raw, err)
+	}
+	return usage, nil
+}
+
+// RSS returns the resident set size of the current process in bytes, read from /proc/self/status
+func RSS() (uint64, error) {
+	f, err := os.Open(procSelfStatus)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "VmRSS:")
+		if !ok {
+			continue
+		}
+
+		// The value is reported in kB, e.g. "VmRSS:	   12345 kB"
+		kb, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid VmRSS %q: %w", value, err)
+		}
+		return kb * 1024, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.New("VmRSS not found in " + procSelfStatus)
+}
+
+// readCgroupFile reads the first of the v2 or v1 files that exists, looking at the root of the
+// mount first, as seen from inside a container, and then in the cgroup of the current process
+func readCgroupFile(root, v2, v1 string) (string, error) {
+	dirs := []string{root}
+	if path := ownCgroupV2Path(); path != "" && path != "/" {
+		dirs = append(dirs, filepath.Join(root, path))
+	}
+
+	for _, dir := range dirs {
+		for _, name := range []string{v2, v1} {
+			raw, err := os.ReadFile(filepath.Join(dir, name))
+			if err == nil {
+				return strings.TrimSpace(string(raw)), nil
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				return "", err
+			}
+		}
+	}
+	return "", fmt.Errorf("no cgroup memory controller found at %s", root)
+}
+
+// ownCgroupV2Path returns the cgroup v2 path of the current process, or an empty string
+func ownCgroupV2Path() string {
+	raw, err := os.ReadFile(procSelfCgroup)
+	if err != nil {
+		return ""
+	}
+
+	for _, line := range strings.Split(string(raw), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/wasilak/profilego/core"
+)
+
+// useProcFixtures points the /proc files at the test fixtures for the duration of the test
+func useProcFixtures(t *testing.T) {
+	t.Helper()
+
+	cgroup, status := procSelfCgroup, procSelfStatus
+	procSelfCgroup = filepath.Join("testdata", "proc", "missing")
+	procSelfStatus = filepath.Join("testdata", "proc", "status")
+	t.Cleanup(func() { procSelfCgroup, procSelfStatus = cgroup, status })
+
+	// Shared readers may have cached readings of the real files
+	readersMu.Lock()
+	clear(readers)
+	readersMu.Unlock()
+}
+
+func TestCgroupLimit(t *testing.T) {
+	useProcFixtures(t)
+
+	testCases := map[string]struct {
+		limit uint64
+		err   error
+	}{
+		"cgroupv2":           {limit: 512 << 20},
+		"cgroupv1":           {limit: 1 << 30},
+		"cgroupv2-unlimited": {err: ErrNoCgroupLimit},
+		"cgroupv1-unlimited": {err: ErrNoCgroupLimit},
+	}
+	for root, tc := range testCases {
+		limit, err := CgroupLimit(filepath.Join("testdata", root))
+		if limit != tc.limit || !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %d (%v), got %d (%v)", root, tc.limit, tc.err, limit, err)
+		}
+	}
+
+	if _, err := CgroupLimit(filepath.Join("testdata", "missing")); err == nil {
+		t.Error("Expected an error without a memory controller")
+	}
+}
+
+func TestCgroupUsage(t *testing.T) {
+	useProcFixtures(t)
+
+	for root, want := range map[string]uint64{"cgroupv2": 128 << 20, "cgroupv1": 256 << 20} {
+		usage, err := CgroupUsage(filepath.Join("testdata", root))
+		if err != nil || usage != want {
+			t.Errorf("%s: expected %d, got %d (%v)", root, want, usage, err)
+		}
+	}
+}
+
+func TestRSS(t *testing.T) {
+	useProcFixtures(t)
+
+	rss, err := RSS()
+	if err != nil || rss != 50<<20 {
+		t.Errorf("Expected 50 MB, got %d (%v)", rss, err)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	useProcFixtures(t)
+
+	v2 := filepath.Join("testdata", "cgroupv2")
+	testCases := map[string]struct {
+		options Options
+		limitMB int64
+		usageMB uint64
+	}{
+		"percentage of the cgroup limit": {Options{LimitMB: 50, LimitPercent: 75, CgroupRoot: v2}, 384, 0},
+		"percentage without a limit":     {Options{LimitMB: 50, LimitPercent: 75, CgroupRoot: filepath.Join("testdata", "cgroupv2-unlimited")}, 50, 0},
+		"cgroup usage":                   {Options{LimitMB: 100, Source: core.MemorySourceCgroup, CgroupRoot: v2}, 100, 128},
+		"rss usage":                      {Options{LimitMB: 100, Source: core.MemorySourceRSS}, 100, 50},
+	}
+	for name, tc := range testCases {
+		if limit := tc.options.Limit(); limit != tc.limitMB {
+			t.Errorf("%s: expected limit %d MB, got %d MB", name, tc.limitMB, limit)
+		}
+		if tc.usageMB == 0 {
+			continue
+		}
+		if usage := tc.options.UsageFunc()(); usage != tc.usageMB<<20 {
+			t.Errorf("%s: expected usage %d MB, got %d bytes", name, tc.usageMB, usage)
+		}
+	}
+
+	// 128 MB of cgroup usage exceeds a limit of 100 MB
+	var memoryErr *MemoryError
+	if err := testCases["cgroup usage"].options.Check(); !errors.As(err, &memoryErr) || memoryErr.CurrentMB != 128 {
+		t.Errorf("Expected *MemoryError for 128 MB, got %v", err)
+	}
+}
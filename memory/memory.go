@@ -1,26 +1,31 @@
 package memory
 
 import (
-	"fmt"
-	"runtime"
 	"sync"
 	"time"
+
+	"github.com/wasilak/profilego/core"
 )
 
+// defaultInterval is how often memory usage is sampled unless set with SetInterval
+const defaultInterval = 5 * time.Second
+
 // MemoryMonitor provides memory monitoring capabilities
 type MemoryMonitor struct {
 	mu         sync.RWMutex
 	limitMB    int64
 	monitoring bool
 	stopCh     chan struct{}
-	usageFunc  func() uint64 // Function to get current memory usage in bytes
+	interval   time.Duration
+	usageFunc  func() uint64         // Function to get current memory usage in bytes
+	onSample   func(usageMB float64) // Called with every sample taken while monitoring
 }
 
 // NewMemoryMonitor creates a new memory monitor with the specified limit in MB
 func NewMemoryMonitor(limitMB int64) *MemoryMonitor {
 	return &MemoryMonitor{
 		limitMB:   limitMB,
-		stopCh:    make(chan struct{}),
+		interval:  defaultInterval,
 		usageFunc: getCurrentMemoryUsage,
 	}
 }
@@ -35,7 +40,8 @@ func (mm *MemoryMonitor) Start() {
 	}
 
 	mm.monitoring = true
-	go mm.monitorLoop()
+	mm.stopCh = make(chan struct{})
+	go mm.monitorLoop(mm.interval, mm.stopCh)
 }
 
 // Stop stops monitoring memory usage
@@ -88,54 +94,69 @@ func (mm *MemoryMonitor) SetLimit(limitMB int64) {
 	mm.limitMB = limitMB
 }
 
+// SetInterval sets how often memory usage is sampled; it takes effect on the next Start
+func (mm *MemoryMonitor) SetInterval(interval time.Duration) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	if interval > 0 {
+		mm.interval = interval
+	}
+}
+
+// SetUsageFunc replaces how memory usage is measured, in bytes
+func (mm *MemoryMonitor) SetUsageFunc(usageFunc func() uint64) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	mm.usageFunc = usageFunc
+}
+
+// OnSample sets a function called with the memory usage in MB on every sample taken while
+// monitoring, e.g. to react when the limit is exceeded
+func (mm *MemoryMonitor) OnSample(fn func(usageMB float64)) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	mm.onSample = fn
+}
+
 // getCurrentMemoryMB gets current memory usage in MB
 func (mm *MemoryMonitor) getCurrentMemoryMB() float64 {
 	usage := mm.usageFunc()
 	return float64(usage) / (1024 * 1024) // Convert bytes to MB
 }
 
-// monitorLoop continuously monitors memory usage
-func (mm *MemoryMonitor) monitorLoop() {
-	ticker := time.NewTicker(5 * time.Second)
+// monitorLoop samples memory usage on every tick and passes it to the OnSample function
+func (mm *MemoryMonitor) monitorLoop(interval time.Duration, stopCh chan struct{}) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			if !mm.IsWithinLimit() {
-				// Could trigger alerts or other actions when limit is exceeded
-				// For now, we just log it (using internal logging)
+			mm.mu.RLock()
+			usageMB, onSample := mm.getCurrentMemoryMB(), mm.onSample
+			mm.mu.RUnlock()
+
+			if onSample != nil {
+				onSample(usageMB)
 			}
-		case <-mm.stopCh:
+		case <-stopCh:
 			return
 		}
 	}
 }
 
-// getCurrentMemoryUsage returns the current memory usage in bytes
+// getCurrentMemoryUsage returns the memory mapped by the Go runtime in bytes
 func getCurrentMemoryUsage() uint64 {
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-	// Using Sys as a representation of the memory reserved from the system
-	return m.Sys
+	usage, _ := readerFor(core.MemorySourceRuntime, DefaultCgroupRoot).Usage()
+	return usage
 }
 
 // CheckMemoryLimit returns an error if memory usage exceeds the limit
 func CheckMemoryLimit(limitMB int64) error {
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-
-	currentMB := float64(m.Sys) / (1024 * 1024)
-
-	if currentMB > float64(limitMB) {
-		return &MemoryError{
-			CurrentMB: currentMB,
-			LimitMB:   float64(limitMB),
-			Message:   fmt.Sprintf("memory usage %.2f MB exceeds limit of %.2f MB", currentMB, float64(limitMB)),
-		}
-	}
-
-	return nil
+	return Options{LimitMB: limitMB}.Check()
 }
 
 // MemoryError represents a memory limit error
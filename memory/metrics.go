@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"fmt"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"github.com/wasilak/profilego/core"
+)
+
+// DefaultCacheTTL is how long a reading is reused before memory usage is read again
+const DefaultCacheTTL = time.Second
+
+// runtime/metrics names the runtime sources are computed from. Unlike runtime.ReadMemStats,
+// reading them does not stop the world.
+const (
+	metricHeapLive = "/gc/heap/live:bytes"
+	metricTotal    = "/memory/classes/total:bytes"
+	metricReleased = "/memory/classes/heap/released:bytes"
+)
+
+// Read returns the current memory usage in bytes measured from source, which defaults to
+// core.MemorySourceRuntime. Readings are cached for DefaultCacheTTL, so it is cheap to call often.
+// The cgroup source reads from DefaultCgroupRoot.
+func Read(source core.MemorySource) (uint64, error) {
+	return readerFor(source, DefaultCgroupRoot).Usage()
+}
+
+// Reader reads memory usage from a source, caching readings so frequent checks stay cheap.
+// It is safe for concurrent use.
+type Reader struct {
+	mu     sync.Mutex
+	read   func() (uint64, error)
+	ttl    time.Duration
+	last   uint64
+	readAt time.Time
+}
+
+// NewReader creates a reader measuring memory usage from source, reusing readings for ttl.
+// cgroupRoot is only used by the cgroup source and defaults to DefaultCgroupRoot.
+func NewReader(source core.MemorySource, cgroupRoot string, ttl time.Duration) (*Reader, error) {
+	if cgroupRoot == "" {
+		cgroupRoot = DefaultCgroupRoot
+	}
+
+	var read func() (uint64, error)
+	switch source {
+	case "", core.MemorySourceRuntime:
+		read = func() (uint64, error) { return readMetric(metricTotal) }
+	case core.MemorySourceHeapLive:
+		read = func() (uint64, error) { return readMetric(metricHeapLive) }
+	case core.MemorySourceRSSEstimate:
+		read = readRSSEstimate
+	case core.MemorySourceCgroup:
+		read = func() (uint64, error) { return CgroupUsage(cgroupRoot) }
+	case core.MemorySourceRSS:
+		read = RSS
+	default:
+		return nil, fmt.Errorf("unknown memory source %q", source)
+	}
+
+	return &Reader{read: read, ttl: ttl}, nil
+}
+
+// Usage returns the memory usage in bytes, read again only once the cached reading expired
+func (r *Reader) Usage() (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.readAt.IsZero() && time.Since(r.readAt) < r.ttl {
+		return r.last, nil
+	}
+
+	usage, err := r.read()
+	if err != nil {
+		return 0, err
+	}
+	r.last, r.readAt = usage, time.Now()
+	return usage, nil
+}
+
+var (
+	readersMu sync.Mutex
+	// readers are shared so checks made by several profilers and the monitor reuse readings
+	readers = make(map[[2]string]*Reader)
+)
+
+// readerFor returns the shared reader for a source, falling back to the runtime source for
+// unknown ones, which configuration validation rejects
+func readerFor(source core.MemorySource, cgroupRoot string) *Reader {
+	readersMu.Lock()
+	defer readersMu.Unlock()
+
+	key := [2]string{string(source), cgroupRoot}
+	if r, ok := readers[key]; ok {
+		return r
+	}
+
+	r, err := NewReader(source, cgroupRoot, DefaultCacheTTL)
+	if err != nil {
+		r, _ = NewReader(core.MemorySourceRuntime, cgroupRoot, DefaultCacheTTL)
+	}
+	readers[key] = r
+	return r
+}
+
+// readRSSEstimate estimates the resident set size as the memory mapped by the runtime minus
+// the heap memory returned to the operating system
+func readRSSEstimate() (uint64, error) {
+	samples := []metrics.Sample{{Name: metricTotal}, {Name: metricReleased}}
+	metrics.Read(samples)
+
+	total, err := sampleValue(samples[0])
+	if err != nil {
+		return 0, err
+	}
+	released, err := sampleValue(samples[1])
+	if err != nil {
+		return 0, err
+	}
+	return total - released, nil
+}
+
+// readMetric reads a single runtime/metrics value in bytes
+func readMetric(name string) (uint64, error) {
+	samples := []metrics.Sample{{Name: name}}
+	metrics.Read(samples)
+	return sampleValue(samples[0])
+}
+
+// sampleValue returns the value of a uint64 sample, or an error if the runtime does not support it
+func sampleValue(sample metrics.Sample) (uint64, error) {
+	if sample.Value.Kind() != metrics.KindUint64 {
+		return 0, fmt.Errorf("runtime metric %s not supported", sample.Name)
+	}
+	return sample.Value.Uint64(), nil
+}
@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/wasilak/profilego/core"
+)
+
+func TestRuntimeSources(t *testing.T) {
+	// The live heap is only known after a garbage collection
+	runtime.GC()
+
+	for _, source := range []core.MemorySource{"", core.MemorySourceRuntime, core.MemorySourceHeapLive, core.MemorySourceRSSEstimate} {
+		usage, err := Read(source)
+		if err != nil {
+			t.Errorf("%q: Read returned error: %v", source, err)
+			continue
+		}
+		if usage == 0 {
+			t.Errorf("%q: expected non-zero usage", source)
+		}
+	}
+
+	total, _ := Read(core.MemorySourceRuntime)
+	if live, _ := Read(core.MemorySourceHeapLive); live > total {
+		t.Errorf("Live heap %d should not exceed mapped memory %d", live, total)
+	}
+}
+
+func TestReaderCachesReadings(t *testing.T) {
+	reader, err := NewReader(core.MemorySourceRuntime, "", time.Hour)
+	if err != nil {
+		t.Fatalf("NewReader returned error: %v", err)
+	}
+
+	reads := 0
+	reader.read = func() (uint64, error) {
+		reads++
+		return uint64(reads), nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if usage, _ := reader.Usage(); usage != 1 {
+			t.Errorf("Expected the cached reading, got %d", usage)
+		}
+	}
+
+	reader.ttl = 0
+	if usage, _ := reader.Usage(); usage != 2 {
+		t.Errorf("Expected a new reading once the cache expired, got %d", usage)
+	}
+}
+
+func TestNewReaderUnknownSource(t *testing.T) {
+	if _, err := NewReader("heap", "", time.Second); err == nil {
+		t.Error("Expected error for unknown memory source")
+	}
+}
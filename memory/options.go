@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+// Options configures how memory usage is measured and what it is limited to
+type Options struct {
+	// LimitMB is the absolute memory limit in MB (0 means no limit)
+	LimitMB int64
+
+	// LimitPercent expresses the limit as a percentage of the cgroup memory limit; it takes
+	// precedence over LimitMB when the cgroup sets a limit
+	LimitPercent float64
+
+	// Source specifies how memory usage is measured (defaults to core.MemorySourceRuntime)
+	Source core.MemorySource
+
+	// CgroupRoot is where the cgroup filesystem is mounted (defaults to DefaultCgroupRoot)
+	CgroupRoot string
+}
+
+// FromConfig returns the memory options of a profiling configuration
+func FromConfig(cfg config.Config) Options {
+	return Options{
+		LimitMB:      cfg.MemoryLimitMB,
+		LimitPercent: cfg.MemoryLimitPercent,
+		Source:       cfg.MemorySource,
+		CgroupRoot:   cfg.CgroupRoot,
+	}
+}
+
+// Limit returns the effective memory limit in MB, 0 meaning no limit. A percentage falls
+// back to LimitMB when no cgroup limit can be read, e.g. outside a container.
+func (o Options) Limit() int64 {
+	if o.LimitPercent <= 0 {
+		return o.LimitMB
+	}
+
+	limit, err := CgroupLimit(o.cgroupRoot())
+	if err != nil {
+		if !errors.Is(err, ErrNoCgroupLimit) {
+			slog.Debug("profilego - failed to read cgroup memory limit, using MemoryLimitMB", "error", err)
+		}
+		return o.LimitMB
+	}
+	return int64(float64(limit) * o.LimitPercent / 100 / (1024 * 1024))
+}
+
+// UsageFunc returns a function measuring memory usage in bytes from the configured source,
+// sharing cached readings with every other user of the same source.
+// If the source cannot be read, the memory mapped by the runtime is used instead.
+func (o Options) UsageFunc() func() uint64 {
+	reader := readerFor(o.Source, o.cgroupRoot())
+
+	return func() uint64 {
+		usage, err := reader.Usage()
+		if err != nil {
+			slog.Debug("profilego - failed to read memory usage, using runtime statistics", "source", o.Source, "error", err)
+			return getCurrentMemoryUsage()
+		}
+		return usage
+	}
+}
+
+// Monitor returns a monitor for the effective limit measuring usage from the configured source
+func (o Options) Monitor() *MemoryMonitor {
+	monitor := NewMemoryMonitor(o.Limit())
+	monitor.SetUsageFunc(o.UsageFunc())
+	return monitor
+}
+
+// Check returns a *MemoryError if memory usage exceeds the effective limit
+func (o Options) Check() error {
+	limitMB := o.Limit()
+	if limitMB <= 0 {
+		return nil
+	}
+
+	currentMB := float64(o.UsageFunc()()) / (1024 * 1024)
+	if currentMB > float64(limitMB) {
+		return &MemoryError{
+			CurrentMB: currentMB,
+			LimitMB:   float64(limitMB),
+			Message:   fmt.Sprintf("memory usage %.2f MB exceeds limit of %.2f MB", currentMB, float64(limitMB)),
+		}
+	}
+	return nil
+}
+
+// cgroupRoot returns the configured cgroup mount point or the default one
+func (o Options) cgroupRoot() string {
+	if o.CgroupRoot == "" {
+		return DefaultCgroupRoot
+	}
+	return o.CgroupRoot
+}
@@ -2,13 +2,8 @@ package profilego
 
 import (
 	"context"
-	"fmt"
 
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
-	otelpyroscope "github.com/grafana/otel-profiling-go"
-
-	"github.com/wasilak/profilego/core"
 )
 
 // WrapTracerProvider wraps a standard TracerProvider with Pyroscope profiling
@@ -20,23 +15,11 @@ import (
 // If profiler is not initialized, returns an error.
 // If backend is not Pyroscope, returns the original provider unchanged.
 func WrapTracerProvider(ctx context.Context, tp trace.TracerProvider) (trace.TracerProvider, error) {
-	if profilerManager == nil {
-		return nil, fmt.Errorf("profiler not initialized - call InitWithConfig first")
-	}
-
-	// Only wrap for Pyroscope backend
-	if profilerManager.Backend() != core.PyroscopeBackend {
-		return tp, nil // Other backends don't need wrapping
+	client := getDefaultClient()
+	if client == nil {
+		return nil, errNotInitialized
 	}
-
-	// Wrap with Pyroscope OTel integration
-	wrappedTP := otelpyroscope.NewTracerProvider(
-		tp,
-		otelpyroscope.WithAppName(profilerManager.AppName()),
-		otelpyroscope.WithPyroscopeURL(profilerManager.ServerAddress()),
-	)
-
-	return wrappedTP, nil
+	return client.WrapTracerProvider(ctx, tp)
 }
 
 // SetTracerProvider wraps and registers a TracerProvider globally.
@@ -45,27 +28,9 @@ func WrapTracerProvider(ctx context.Context, tp trace.TracerProvider) (trace.Tra
 // For Pyroscope backend, wraps the provided TracerProvider with Pyroscope integration.
 // For other backends, registers the provider unchanged.
 func SetTracerProvider(ctx context.Context, tp trace.TracerProvider) error {
-	wrappedTP, err := WrapTracerProvider(ctx, tp)
-	if err != nil {
-		return err
+	client := getDefaultClient()
+	if client == nil {
+		return errNotInitialized
 	}
-	otel.SetTracerProvider(wrappedTP)
-	return nil
-}
-
-// handleOTelTracerProvider handles OTel integration if provided in config.
-// The tp parameter should be a trace.TracerProvider instance.
-func handleOTelTracerProvider(ctx context.Context, tp interface{}) error {
-	// Type assert to trace.TracerProvider
-	tracerProvider, ok := tp.(trace.TracerProvider)
-	if !ok {
-		return fmt.Errorf("OTelTracerProvider is not a valid trace.TracerProvider")
-	}
-
-	wrappedTP, err := WrapTracerProvider(ctx, tracerProvider)
-	if err != nil {
-		return err
-	}
-
-	return SetTracerProvider(ctx, wrappedTP)
+	return client.SetTracerProvider(ctx, tp)
 }
@@ -78,7 +78,7 @@ func TestWrapTracerProviderNotInitialized(t *testing.T) {
 	ctx := context.Background()
 
 	// Ensure profiler is not initialized
-	profilerManager = nil
+	defaultClient = nil
 
 	// Create a standard TracerProvider
 	standardTP := trace.NewTracerProvider()
@@ -158,7 +158,7 @@ func TestOTelTracerProviderInvalidType(t *testing.T) {
 	defer Stop()
 
 	// Try to handle invalid OTel type
-	if err := handleOTelTracerProvider(ctx, "not-a-tracer-provider"); err == nil {
+	if err := getDefaultClient().handleOTelTracerProvider(ctx, "not-a-tracer-provider"); err == nil {
 		t.Fatal("handleOTelTracerProvider should fail with invalid type")
 	}
 }
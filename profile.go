@@ -2,7 +2,8 @@ package profilego
 
 import (
 	"context"
-	"errors"
+	"sync"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 
@@ -71,127 +72,279 @@ func (c Config) toNewConfig() config.Config {
 	}
 }
 
-// profilerManager is the global profiler manager instance
-var profilerManager *manager.ProfilerManager
+// defaultMu guards defaultClient
+var defaultMu sync.RWMutex
 
-// globalTagger is the global tagger instance for adding tags to profiles
-var globalTagger core.Tagger
-
-// globalContext is the context used throughout the profiler lifecycle
-var globalContext context.Context
+// defaultClient is the client used by the package-level functions
+var defaultClient *Client
 
 // InitWithConfig initializes profiling with the new configuration format.
 // If ctx is nil, context.Background() is used.
 // Returns the context used by the profiler for use throughout the application.
+// The package-level functions use the client created here; use New for independent clients.
 func InitWithConfig(ctx context.Context, cfg config.Config) (context.Context, error) {
 	// Use the configuration as provided by the user
 	// If they want defaults, they should provide them explicitly
 
-	if ctx == nil {
-		ctx = context.Background()
+	client, err := New(ctx, cfg)
+	if err != nil {
+		return nil, err
 	}
-	globalContext = ctx
 
-	profilerManager = manager.NewProfilerManager(cfg)
+	defaultMu.Lock()
+	defaultClient = client
+	defaultMu.Unlock()
+
+	return client.Context(), nil
+}
+
+// getDefaultClient returns the client created by InitWithConfig, or nil before initialization
+func getDefaultClient() *Client {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultClient
+}
 
-	// Create and add the appropriate profiler
-	var profiler core.Profiler
-	var err error
-	switch cfg.Backend {
-	case core.PyroscopeBackend:
-		profiler, err = profiler_pkg.NewPyroscopeProfiler(cfg)
-		if err != nil {
-			return nil, err
+// newTagger returns the tagger matching the configured backends, combining
+// the taggers of the different backend types when several are run
+func newTagger(cfg config.Config) core.Tagger {
+	var taggers []core.Tagger
+	seen := make(map[core.BackendType]bool)
+	for _, b := range cfg.EffectiveBackends() {
+		if seen[b.Type] {
+			continue
 		}
-		// Initialize the global tagger for Pyroscope
-		globalTagger = profiler_pkg.NewPyroscopeTagger()
-	case core.PprofBackend:
-		profiler, err = profiler_pkg.NewPprofProfiler(cfg)
-		if err != nil {
-			return nil, err
+		seen[b.Type] = true
+
+		if b.Type == core.PyroscopeBackend {
+			taggers = append(taggers, profiler_pkg.NewPyroscopeTagger())
+		} else {
+			taggers = append(taggers, profiler_pkg.NewPprofTagger())
 		}
-		// Initialize the global tagger for pprof
-		globalTagger = profiler_pkg.NewPprofTagger()
-	default:
-		return nil, errors.New("unsupported backend type: " + string(cfg.Backend))
 	}
 
-	if err := profilerManager.AddProfiler(profiler); err != nil {
-		return nil, err
+	if len(taggers) == 1 {
+		return taggers[0]
 	}
+	return profiler_pkg.NewMultiTagger(taggers...)
+}
 
-	if err := profilerManager.Init(); err != nil {
-		return nil, err
+// usesBackend returns whether any of the configured backends is of the given type
+func usesBackend(cfg config.Config, backend core.BackendType) bool {
+	for _, b := range cfg.EffectiveBackends() {
+		if b.Type == backend {
+			return true
+		}
 	}
+	return false
+}
 
-	// Wrap and register OTel TracerProvider if provided and using Pyroscope backend
-	if cfg.OTelTracerProvider != nil && cfg.Backend == core.PyroscopeBackend {
-		if err := handleOTelTracerProvider(ctx, cfg.OTelTracerProvider); err != nil {
-			return nil, err
-		}
+// ApplyConfig replaces the profiling configuration at runtime, restarting only the
+// profilers affected by the change
+func ApplyConfig(cfg config.Config) error {
+	client := getDefaultClient()
+	if client == nil {
+		return errNotInitialized
 	}
+	return client.ApplyConfig(cfg)
+}
 
-	return globalContext, nil
+// WatchConfig reloads the configuration with load every interval and applies it when it changes,
+// e.g. WatchConfig(ctx, time.Minute, manager.FileLoader(path, config.Config{})).
+// It runs in the background until ctx is cancelled.
+func WatchConfig(ctx context.Context, interval time.Duration, load manager.ConfigLoader) error {
+	client := getDefaultClient()
+	if client == nil {
+		return errNotInitialized
+	}
+	client.WatchConfig(ctx, interval, load)
+	return nil
 }
 
-// Stop stops profiling gracefully
-// If ctx is provided, it's used for shutdown operations; otherwise the global context is used
+// Stop stops profiling gracefully, waiting for final uploads until ctx is done or the configured
+// Timeout elapses, whichever comes first. A backend that fails to flush in time is reported with a
+// *core.TimeoutError. ctx is optional.
 func Stop(ctx ...context.Context) error {
-	if profilerManager != nil {
-		// If a context is provided, use it; otherwise use the global context
-		if len(ctx) > 0 && ctx[0] != nil {
-			globalContext = ctx[0]
-		}
-		return profilerManager.Stop()
+	if client := getDefaultClient(); client != nil {
+		return client.Stop(optionalContext(ctx))
 	}
 	return nil
 }
 
 // Context returns the context associated with the profiler
-// This context is cancelled when Stop() is called
 func Context() context.Context {
-	if globalContext != nil {
-		return globalContext
+	if client := getDefaultClient(); client != nil {
+		return client.Context()
 	}
 	return context.Background()
 }
 
 // IsRunning returns whether profiling is currently active
 func IsRunning() bool {
-	if profilerManager != nil {
-		return profilerManager.IsRunning()
+	if client := getDefaultClient(); client != nil {
+		return client.IsRunning()
 	}
 	return false
 }
 
 // Start profiling if not already running
-func Start() error {
-	if profilerManager != nil {
-		return profilerManager.Start()
+// Profiling can be started again after Stop. ctx is optional.
+func Start(ctx ...context.Context) error {
+	if client := getDefaultClient(); client != nil {
+		return client.Start(optionalContext(ctx))
+	}
+	return errNotInitialized
+}
+
+// Pause temporarily stops profiling; Resume continues it. ctx is optional.
+func Pause(ctx ...context.Context) error {
+	if client := getDefaultClient(); client != nil {
+		return client.Pause(optionalContext(ctx))
+	}
+	return errNotInitialized
+}
+
+// Resume continues profiling after Pause. ctx is optional.
+func Resume(ctx ...context.Context) error {
+	if client := getDefaultClient(); client != nil {
+		return client.Resume(optionalContext(ctx))
+	}
+	return errNotInitialized
+}
+
+// optionalContext returns the first of the optional contexts passed to a package function,
+// or context.Background() if there is none
+func optionalContext(ctx []context.Context) context.Context {
+	if len(ctx) == 0 || ctx[0] == nil {
+		return context.Background()
+	}
+	return ctx[0]
+}
+
+// States returns the lifecycle state of every profiler by name
+func States() map[string]core.State {
+	if client := getDefaultClient(); client != nil {
+		return client.States()
+	}
+	return nil
+}
+
+// Status returns a snapshot of the profilers, including automatic restart attempts
+// and whether profiling runs degraded
+func Status() manager.Status {
+	if client := getDefaultClient(); client != nil {
+		return client.Status()
+	}
+	return manager.Status{}
+}
+
+// Stats returns the estimated overhead of profiling of the default client
+func Stats() manager.Stats {
+	if client := getDefaultClient(); client != nil {
+		return client.Stats()
+	}
+	return manager.Stats{}
+}
+
+// TagStats returns the number of tags dropped or collapsed by the TagPolicy of the default client, per key
+func TagStats() core.TagPolicyStats {
+	if client := getDefaultClient(); client != nil {
+		return client.TagStats()
+	}
+	return core.TagPolicyStats{}
+}
+
+// OnStateChange registers a listener called after every profiler state change
+// Listeners are called synchronously and must not call back into profilego
+func OnStateChange(listener core.StateListener) error {
+	client := getDefaultClient()
+	if client == nil {
+		return errNotInitialized
 	}
-	return errors.New("profiler not initialized")
+	client.OnStateChange(listener)
+	return nil
 }
 
-// AddTag adds a key-value pair tag to the current profiling context
-// This is a no-op for pprof backend as it doesn't support runtime tagging
-func AddTag(key, value string) error {
-	if globalTagger == nil {
-		return errors.New("profiler not initialized")
+// OnMemoryEvent registers a listener called after profile types are suspended or resumed
+// because of MemoryLimitMB. Listeners are called synchronously and must not call back into profilego.
+func OnMemoryEvent(listener manager.MemoryListener) error {
+	client := getDefaultClient()
+	if client == nil {
+		return errNotInitialized
 	}
-	return globalTagger.AddTag(key, value)
+	client.OnMemoryEvent(listener)
+	return nil
+}
+
+// AddTag adds a key-value pair tag to the labels of ctx and sets them on the calling goroutine,
+// so the tag applies to its remaining work and the goroutines it starts.
+// The returned context carries the tag; pass it on so later tags are added to it.
+// If ctx is nil, the global profiler context is used
+func AddTag(ctx context.Context, key, value string) (context.Context, error) {
+	client := getDefaultClient()
+	if client == nil {
+		return ctx, errNotInitialized
+	}
+	return client.AddTag(ctx, key, value)
+}
+
+// AddTagScoped adds a tag like AddTag and returns a release function restoring the labels
+// of ctx on the calling goroutine, e.g.
+//
+//	ctx, release, err := profilego.AddTagScoped(ctx, "user_id", userID)
+//	defer release()
+//
+// If ctx is nil, the global profiler context is used
+func AddTagScoped(ctx context.Context, key, value string) (context.Context, func(), error) {
+	client := getDefaultClient()
+	if client == nil {
+		return ctx, func() {}, errNotInitialized
+	}
+	return client.AddTagScoped(ctx, key, value)
 }
 
 // TagWrapper executes a function with additional profiling tags
 // The tags are only applied during the execution of the function
 // The provided context is passed to the function
-// For pprof backend, this simply executes the function without adding tags
+// For pprof backend, the tags are recorded as pprof labels by CPU and goroutine profiles
 // If ctx is nil, the global profiler context is used
 func TagWrapper(ctx context.Context, key, value string, fn func(context.Context) error) error {
-	if globalTagger == nil {
-		return errors.New("profiler not initialized")
+	client := getDefaultClient()
+	if client == nil {
+		return errNotInitialized
 	}
-	if ctx == nil {
-		ctx = Context()
+	return client.TagWrapper(ctx, key, value, fn)
+}
+
+// TagWrapperLabels executes a function with several additional profiling tags at once,
+// merged with the tags already present in ctx, e.g.
+//
+//	profilego.TagWrapperLabels(ctx, core.NewTags("route", route, "method", method), handler)
+//
+// If ctx is nil, the global profiler context is used
+func TagWrapperLabels(ctx context.Context, tags core.Tags, fn func(context.Context) error) error {
+	client := getDefaultClient()
+	if client == nil {
+		return errNotInitialized
+	}
+	return client.TagWrapperLabels(ctx, tags, fn)
+}
+
+// SetGlobalTag adds or replaces a static tag at runtime, e.g. a feature flag set or a leader/follower role.
+// The Pyroscope backend applies it to subsequent uploads without restarting.
+func SetGlobalTag(key, value string) error {
+	client := getDefaultClient()
+	if client == nil {
+		return errNotInitialized
+	}
+	return client.SetGlobalTag(key, value)
+}
+
+// RemoveGlobalTag removes a static tag, configured or set at runtime, from subsequent uploads
+func RemoveGlobalTag(key string) error {
+	client := getDefaultClient()
+	if client == nil {
+		return errNotInitialized
 	}
-	return globalTagger.TagWrapper(ctx, key, value, fn)
+	return client.RemoveGlobalTag(key)
 }
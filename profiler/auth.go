@@ -0,0 +1,109 @@
+package profiler
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wasilak/profilego/config"
+)
+
+// credentials holds the resolved authentication settings for uploads
+type credentials struct {
+	basicAuthUser     string
+	basicAuthPassword string
+	tenantID          string
+	headers           map[string]string
+	bearerToken       func() (string, error)
+}
+
+// newCredentials resolves the authentication settings, reading secrets from files where configured
+func newCredentials(cfg config.Config) (credentials, error) {
+	creds := credentials{
+		basicAuthUser: cfg.BasicAuthUser,
+		tenantID:      cfg.TenantID,
+		headers:       cfg.HTTPHeaders,
+	}
+
+	creds.basicAuthPassword = cfg.BasicAuthPassword
+	if cfg.BasicAuthPasswordFile != "" {
+		password, err := readSecret(cfg.BasicAuthPasswordFile)
+		if err != nil {
+			return credentials{}, err
+		}
+		creds.basicAuthPassword = password
+	}
+
+	switch {
+	case cfg.BearerTokenFile != "":
+		token := &secretFile{path: cfg.BearerTokenFile}
+		if _, err := token.get(); err != nil {
+			return credentials{}, err
+		}
+		creds.bearerToken = token.get
+	case cfg.BearerToken != "":
+		creds.bearerToken = func() (string, error) { return cfg.BearerToken, nil }
+	}
+
+	return creds, nil
+}
+
+// readSecret reads a secret from a file, ignoring surrounding whitespace
+func readSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// secretFile reads a secret from a mounted file and re-reads it when the file changes,
+// so rotated tokens are picked up without restarting the profiler
+type secretFile struct {
+	mu    sync.Mutex
+	path  string
+	value string
+	mod   time.Time
+}
+
+// get returns the current secret
+func (s *secretFile) get() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mod := modTime(s.path)
+	if s.value != "" && mod.Equal(s.mod) {
+		return s.value, nil
+	}
+
+	value, err := readSecret(s.path)
+	if err != nil {
+		return "", err
+	}
+	s.value, s.mod = value, mod
+	return value, nil
+}
+
+// bearerTransport adds a bearer token to every request
+type bearerTransport struct {
+	base  http.RoundTripper
+	token func() (string, error)
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.token()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,143 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+// headerRecorder records the headers of requests received by a test server
+type headerRecorder struct {
+	mu      sync.Mutex
+	headers []http.Header
+}
+
+func (h *headerRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.headers = append(h.headers, r.Header.Clone())
+}
+
+// last returns the headers of the most recent request
+func (h *headerRecorder) last(t *testing.T) http.Header {
+	t.Helper()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.headers) == 0 {
+		t.Fatal("No requests received")
+	}
+	return h.headers[len(h.headers)-1]
+}
+
+func TestPyroscopeProfilerSendsCredentials(t *testing.T) {
+	recorder := &headerRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Config{
+		ApplicationName:       "test-app",
+		Backend:               core.PyroscopeBackend,
+		ServerAddress:         server.URL,
+		ProfileTypes:          []core.ProfileType{core.ProfileCPU, core.ProfileGoroutines},
+		BasicAuthUser:         "profiler",
+		BasicAuthPasswordFile: passwordFile,
+		TenantID:              "team-a",
+		HTTPHeaders:           map[string]string{"X-Cluster": "eu-1"},
+	}
+	profiler, err := NewPyroscopeProfiler(cfg)
+	if err != nil {
+		t.Fatalf("NewPyroscopeProfiler returned error: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := profiler.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	// Flush waits for the uploads; it requires the CPU collector to be running
+	profiler.profiler.Flush(true)
+	if err := profiler.Stop(ctx); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+
+	headers := recorder.last(t)
+	req := &http.Request{Header: headers}
+	user, password, ok := req.BasicAuth()
+	if !ok || user != "profiler" || password != "s3cret" {
+		t.Errorf("Expected basic auth profiler:s3cret, got %q:%q (ok=%v)", user, password, ok)
+	}
+	if got := headers.Get("X-Scope-OrgID"); got != "team-a" {
+		t.Errorf("Expected X-Scope-OrgID 'team-a', got '%s'", got)
+	}
+	if got := headers.Get("X-Cluster"); got != "eu-1" {
+		t.Errorf("Expected X-Cluster 'eu-1', got '%s'", got)
+	}
+}
+
+func TestBearerTokenFileIsReloaded(t *testing.T) {
+	recorder := &headerRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	writeToken := func(token string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(tokenFile, []byte(token+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(tokenFile, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeToken("first", time.Now().Add(-time.Minute))
+
+	creds, err := newCredentials(config.Config{BearerTokenFile: tokenFile})
+	if err != nil {
+		t.Fatalf("newCredentials returned error: %v", err)
+	}
+	client, err := newHTTPClient(config.Config{}, creds)
+	if err != nil {
+		t.Fatalf("newHTTPClient returned error: %v", err)
+	}
+
+	for _, expected := range []string{"first", "second"} {
+		if expected == "second" {
+			writeToken("second", time.Now())
+		}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if got := recorder.last(t).Get("Authorization"); got != "Bearer "+expected {
+			t.Errorf("Expected Authorization 'Bearer %s', got '%s'", expected, got)
+		}
+	}
+}
+
+func TestNewCredentialsMissingFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	for name, cfg := range map[string]config.Config{
+		"password": {BasicAuthUser: "user", BasicAuthPasswordFile: missing},
+		"token":    {BearerTokenFile: missing},
+	} {
+		if _, err := NewPyroscopeProfiler(cfg); err == nil {
+			t.Errorf("%s: NewPyroscopeProfiler should fail for a missing secret file", name)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package profiler
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampLayout is the layout used for the {timestamp} placeholder
+const timestampLayout = "20060102T150405.000Z"
+
+// profileFileTypes are the profile names used for the {type} placeholder
+var profileFileTypes = []string{"cpu", "allocs", "heap", "goroutine", "mutex", "block"}
+
+// placeholderPattern matches the placeholders of a filename template
+var placeholderPattern = regexp.MustCompile(`\{(app|type|timestamp|hostname|pid|seq)\}`)
+
+// filenameTemplate renders profile file names from a template such as
+// "{app}_{type}_{timestamp}.pprof"
+type filenameTemplate struct {
+	dir      string
+	template string
+	app      string
+	hostname string
+	pid      int
+}
+
+// newFilenameTemplate creates a filename template for the given output directory and application
+func newFilenameTemplate(dir, template, app string) filenameTemplate {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return filenameTemplate{
+		dir:      dir,
+		template: template,
+		app:      app,
+		hostname: hostname,
+		pid:      os.Getpid(),
+	}
+}
+
+// path returns the full path of the file for the given profile type, time and sequence number
+func (ft filenameTemplate) path(profileType string, now time.Time, seq uint64) string {
+	r := strings.NewReplacer(
+		"{app}", ft.app,
+		"{type}", profileType,
+		"{timestamp}", now.UTC().Format(timestampLayout),
+		"{hostname}", ft.hostname,
+		"{pid}", strconv.Itoa(ft.pid),
+		"{seq}", strconv.FormatUint(seq, 10),
+	)
+	return filepath.Join(ft.dir, r.Replace(ft.template))
+}
+
+// glob returns a pattern matching files written by this application on this host,
+// including files left behind by previous processes. An empty profileType matches all types.
+func (ft filenameTemplate) glob(profileType string) string {
+	if profileType == "" {
+		profileType = "*"
+	}
+
+	r := strings.NewReplacer(
+		"{app}", globEscape(ft.app),
+		"{type}", profileType,
+		"{timestamp}", "*",
+		"{hostname}", globEscape(ft.hostname),
+		"{pid}", "*",
+		"{seq}", "*",
+	)
+	return filepath.Join(ft.dir, r.Replace(globEscape(ft.template)))
+}
+
+// globEscape escapes characters that have a special meaning in filepath.Match patterns
+func globEscape(s string) string {
+	r := strings.NewReplacer("*", `\*`, "?", `\?`, "[", `\[`)
+	return r.Replace(s)
+}
+
+// matches returns whether path, found with glob, was written by this application on this host.
+// Globs match any text for the variable placeholders, so a glob for "my-app" also matches the
+// files of "my-app_x" sharing the directory; matches checks each placeholder strictly.
+func (ft filenameTemplate) matches(path, profileType string) bool {
+	rel, err := filepath.Rel(ft.dir, path)
+	if err != nil {
+		return false
+	}
+	return ft.pattern(profileType).MatchString(rel)
+}
+
+// pattern returns an anchored regular expression matching the names rendered by path.
+// An empty profileType matches all types.
+func (ft filenameTemplate) pattern(profileType string) *regexp.Regexp {
+	types := profileFileTypes
+	if profileType != "" {
+		types = []string{profileType}
+	}
+	quotedTypes := make([]string, len(types))
+	for i, t := range types {
+		quotedTypes[i] = regexp.QuoteMeta(t)
+	}
+
+	placeholders := map[string]string{
+		"{app}":       regexp.QuoteMeta(ft.app),
+		"{type}":      "(?:" + strings.Join(quotedTypes, "|") + ")",
+		"{timestamp}": `\d{8}T\d{6}\.\d{3}Z`,
+		"{hostname}":  regexp.QuoteMeta(ft.hostname),
+		"{pid}":       `\d+`,
+		"{seq}":       `\d+`,
+	}
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	last := 0
+	for _, loc := range placeholderPattern.FindAllStringIndex(ft.template, -1) {
+		sb.WriteString(regexp.QuoteMeta(ft.template[last:loc[0]]))
+		sb.WriteString(placeholders[ft.template[loc[0]:loc[1]]])
+		last = loc[1]
+	}
+	sb.WriteString(regexp.QuoteMeta(ft.template[last:]))
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
@@ -0,0 +1,63 @@
+package profiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFilenameTemplatePath(t *testing.T) {
+	ft := newFilenameTemplate("/tmp/profiles", "{app}-{hostname}-{pid}-{type}-{seq}-{timestamp}.pprof", "test-app")
+	ft.hostname = "host"
+	ft.pid = 42
+
+	now := time.Date(2024, 5, 6, 7, 8, 9, 10_000_000, time.UTC)
+	expected := filepath.Join("/tmp/profiles", "test-app-host-42-cpu-3-20240506T070809.010Z.pprof")
+	if got := ft.path("cpu", now, 3); got != expected {
+		t.Errorf("Expected path '%s', got '%s'", expected, got)
+	}
+}
+
+func TestFilenameTemplateGlob(t *testing.T) {
+	dir := t.TempDir()
+	ft := newFilenameTemplate(dir, "{app}_{type}_{pid}_{seq}_{timestamp}.pprof", "test-app")
+
+	now := time.Now()
+	for _, name := range []string{
+		ft.path("cpu", now, 1),
+		ft.path("heap", now, 1),
+		filepath.Join(dir, "other-app_cpu_1_1_20240506T070809.010Z.pprof"),
+	} {
+		if err := os.WriteFile(name, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, _ := filepath.Glob(ft.glob(""))
+	if len(all) != 2 {
+		t.Errorf("Expected 2 files for all types, got %d: %v", len(all), all)
+	}
+
+	cpu, _ := filepath.Glob(ft.glob("cpu"))
+	if len(cpu) != 1 {
+		t.Errorf("Expected 1 cpu file, got %d: %v", len(cpu), cpu)
+	}
+}
+
+func TestFilenameTemplateMatches(t *testing.T) {
+	ft := newFilenameTemplate("/tmp/profiles", "{app}_{type}_{pid}_{seq}_{timestamp}.pprof", "my-app")
+	now := time.Now()
+
+	if !ft.matches(ft.path("cpu", now, 1), "") || !ft.matches(ft.path("heap", now, 1), "heap") {
+		t.Error("Expected the files of the application to match")
+	}
+	if ft.matches(ft.path("heap", now, 1), "cpu") {
+		t.Error("Expected a heap file not to match the cpu type")
+	}
+
+	other := newFilenameTemplate("/tmp/profiles", ft.template, "my-app_x")
+	if ft.matches(other.path("cpu", now, 1), "") {
+		t.Error("Expected the file of an application sharing the name prefix not to match")
+	}
+}
@@ -0,0 +1,85 @@
+package profiler
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// globalTags holds the static tags of a profiler, which can change while uploads are in flight
+type globalTags struct {
+	tags atomic.Pointer[map[string]string]
+}
+
+// newGlobalTags creates global tags starting with a copy of tags
+func newGlobalTags(tags map[string]string) *globalTags {
+	gt := &globalTags{}
+	gt.store(tags)
+	return gt
+}
+
+// load returns the current tags, which must not be modified
+func (gt *globalTags) load() map[string]string {
+	return *gt.tags.Load()
+}
+
+// store replaces the current tags with a copy of tags
+func (gt *globalTags) store(tags map[string]string) {
+	tags = maps.Clone(tags)
+	gt.tags.Store(&tags)
+}
+
+// globalTagsTransport rewrites the application name of Pyroscope uploads, which carries the static
+// tags, so tags changed at runtime apply to subsequent uploads without restarting the session
+type globalTagsTransport struct {
+	base    http.RoundTripper
+	started map[string]string // tags the session was started with
+	tags    *globalTags
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *globalTagsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	current := t.tags.load()
+	name := req.URL.Query().Get("name")
+	if name == "" || maps.Equal(t.started, current) {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	query := req.URL.Query()
+	query.Set("name", retagName(name, t.started, current))
+	req.URL.RawQuery = query.Encode()
+	return t.base.RoundTrip(req)
+}
+
+// retagName replaces the started tags in an application name of the form app{key=value,...}
+// with the current ones, keeping the tags Pyroscope adds itself such as the session ID
+func retagName(name string, started, current map[string]string) string {
+	app, labels, _ := strings.Cut(name, "{")
+	labels = strings.TrimSuffix(labels, "}")
+
+	tags := make(map[string]string)
+	for _, label := range strings.Split(labels, ",") {
+		if key, value, ok := strings.Cut(label, "="); ok {
+			tags[key] = value
+		}
+	}
+	for key := range started {
+		delete(tags, key)
+	}
+	maps.Copy(tags, current)
+
+	var sb strings.Builder
+	sb.WriteString(app)
+	sb.WriteString("{")
+	for i, key := range slices.Sorted(maps.Keys(tags)) {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(key + "=" + tags[key])
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
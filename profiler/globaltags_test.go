@@ -0,0 +1,101 @@
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+func TestRetagName(t *testing.T) {
+	tests := []struct {
+		name     string
+		started  map[string]string
+		current  map[string]string
+		expected string
+	}{
+		{
+			name:     "test-app.cpu{__session_id__=abc,env=prod}",
+			started:  map[string]string{"env": "prod"},
+			current:  map[string]string{"env": "prod", "canary": "true"},
+			expected: "test-app.cpu{__session_id__=abc,canary=true,env=prod}",
+		},
+		{
+			name:     "test-app.cpu{__session_id__=abc,env=prod,role=leader}",
+			started:  map[string]string{"env": "prod", "role": "leader"},
+			current:  map[string]string{"role": "follower"},
+			expected: "test-app.cpu{__session_id__=abc,role=follower}",
+		},
+		{
+			name:     "test-app.cpu{region=eu,env=prod}",
+			started:  map[string]string{"env": "prod"},
+			current:  nil,
+			expected: "test-app.cpu{region=eu}",
+		},
+		{
+			name:     "test-app.cpu{}",
+			started:  nil,
+			current:  map[string]string{"env": "prod"},
+			expected: "test-app.cpu{env=prod}",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := retagName(tt.name, tt.started, tt.current); got != tt.expected {
+			t.Errorf("retagName(%q) = %q, expected %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestPyroscopeProfilerGlobalTags(t *testing.T) {
+	var mu sync.Mutex
+	var names []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		names = append(names, r.URL.Query().Get("name"))
+	}))
+	defer server.Close()
+
+	profiler, err := NewPyroscopeProfiler(config.Config{
+		ApplicationName: "test-app",
+		Backend:         core.PyroscopeBackend,
+		ServerAddress:   server.URL,
+		ProfileTypes:    []core.ProfileType{core.ProfileCPU},
+		Tags:            map[string]string{"env": "prod", "role": "leader"},
+	})
+	if err != nil {
+		t.Fatalf("NewPyroscopeProfiler returned error: %v", err)
+	}
+	if err := profiler.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	if err := profiler.SetGlobalTag("canary", "true"); err != nil {
+		t.Fatalf("SetGlobalTag returned error: %v", err)
+	}
+	if err := profiler.RemoveGlobalTag("role"); err != nil {
+		t.Fatalf("RemoveGlobalTag returned error: %v", err)
+	}
+
+	// Stopping uploads the profiles collected so far
+	if err := profiler.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(names) == 0 {
+		t.Fatal("Expected profiles to be uploaded on Stop")
+	}
+	for _, name := range names {
+		if !strings.Contains(name, "canary=true") || !strings.Contains(name, "env=prod") || strings.Contains(name, "role=") {
+			t.Errorf("Expected the upload to carry the tags set at runtime, got %q", name)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package profiler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	runtimepprof "runtime/pprof"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+// HTTPProfiler implements the Profiler interface by serving the net/http/pprof endpoints
+// on ServerAddress, so profiles can be pulled on demand with go tool pprof
+type HTTPProfiler struct {
+	mu        sync.RWMutex
+	config    config.Config
+	server    *http.Server
+	listener  net.Listener
+	state     *core.StateMachine
+	suspended suspension
+	rates     samplingRates
+	reduced   bool // sampling reduced to stay within the overhead budget
+	overhead  overhead
+	// suspendMu also guards suspended so handlers do not wait for hp.mu, which
+	// Stop holds while it waits for in-flight requests
+	suspendMu sync.RWMutex
+}
+
+// NewHTTPProfiler creates a new HTTP profiler
+func NewHTTPProfiler(cfg config.Config) (*HTTPProfiler, error) {
+	if cfg.ServerAddress == "" {
+		return nil, errors.New("listen address not provided for http backend")
+	}
+
+	return &HTTPProfiler{
+		config:    cfg,
+		state:     core.NewStateMachine("http"),
+		suspended: make(suspension),
+		rates:     newSamplingRates(cfg),
+	}, nil
+}
+
+// Name returns the profiler's identifier
+func (hp *HTTPProfiler) Name() string {
+	return "http"
+}
+
+// Start begins serving the profiling endpoints
+func (hp *HTTPProfiler) Start(ctx context.Context) error {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	if hp.state.Is(core.StateRunning) {
+		return nil // Already running
+	}
+	if err := hp.state.Transition(core.StateStarting); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", hp.config.ServerAddress)
+	if err != nil {
+		return hp.state.Fail(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", hp.unlessSuspended(hp.profileCPU, core.ProfileCPU))
+	mux.HandleFunc("/debug/pprof/allocs", hp.unlessSuspended(pprof.Handler("allocs").ServeHTTP, core.ProfileAllocObjects, core.ProfileAllocSpace))
+	mux.HandleFunc("/debug/pprof/heap", hp.unlessSuspended(pprof.Handler("heap").ServeHTTP,
+		core.ProfileInuseObjects, core.ProfileInuseSpace, core.ProfileAllocObjects, core.ProfileAllocSpace))
+	mux.HandleFunc("/debug/pprof/goroutine", hp.unlessSuspended(pprof.Handler("goroutine").ServeHTTP, core.ProfileGoroutines))
+	mux.HandleFunc("/debug/pprof/block", hp.unlessSuspended(pprof.Handler("block").ServeHTTP, core.ProfileBlockCount, core.ProfileBlockDuration))
+	mux.HandleFunc("/debug/pprof/mutex", hp.unlessSuspended(pprof.Handler("mutex").ServeHTTP, core.ProfileMutexCount, core.ProfileMutexDuration))
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	readHeaderTimeout := hp.config.Timeout
+	if readHeaderTimeout <= 0 {
+		readHeaderTimeout = config.DefaultConfig.Timeout
+	}
+	server := &http.Server{Handler: hp.accounted(mux), ReadHeaderTimeout: readHeaderTimeout}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("profilego - pprof HTTP server failed", "address", listener.Addr().String(), "error", err)
+		}
+	}()
+
+	setRuntimeProfiles(hp, hp.rates, hp.suspended.active(hp.config.ProfileTypes), hp.reduced)
+
+	hp.server = server
+	hp.listener = listener
+	return hp.state.Transition(core.StateRunning)
+}
+
+// Stop shuts the server down, waiting for in-flight requests until ctx is done
+func (hp *HTTPProfiler) Stop(ctx context.Context) error {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	if hp.state.Is(core.StateInitialized, core.StateStopped) {
+		return nil
+	}
+	if err := hp.state.Transition(core.StateStopping); err != nil {
+		return err
+	}
+
+	if err := hp.shutdown(ctx); err != nil {
+		return hp.state.Fail(err)
+	}
+	return hp.state.Transition(core.StateStopped)
+}
+
+// Pause stops serving the endpoints until Resume
+func (hp *HTTPProfiler) Pause(ctx context.Context) error {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	if hp.state.Is(core.StatePaused) {
+		return nil
+	}
+	if err := hp.state.Transition(core.StatePaused); err != nil {
+		return err
+	}
+
+	if err := hp.shutdown(ctx); err != nil {
+		return hp.state.Fail(err)
+	}
+	return nil
+}
+
+// Resume after pause
+func (hp *HTTPProfiler) Resume(ctx context.Context) error {
+	return hp.Start(ctx)
+}
+
+// SuspendProfileTypes stops the sampling behind the given profile types; their endpoints
+// respond with 503 Service Unavailable until the types are resumed
+func (hp *HTTPProfiler) SuspendProfileTypes(ctx context.Context, types []core.ProfileType) error {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	hp.suspendMu.Lock()
+	hp.suspended.set(hp.config.ProfileTypes, types, true)
+	hp.suspendMu.Unlock()
+
+	if hp.state.Is(core.StateRunning) {
+		setRuntimeProfiles(hp, hp.rates, hp.suspended.active(hp.config.ProfileTypes), hp.reduced)
+	}
+	return nil
+}
+
+// ResumeProfileTypes resumes suspended profile types
+func (hp *HTTPProfiler) ResumeProfileTypes(ctx context.Context, types []core.ProfileType) error {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	hp.suspendMu.Lock()
+	hp.suspended.set(hp.config.ProfileTypes, types, false)
+	hp.suspendMu.Unlock()
+
+	if hp.state.Is(core.StateRunning) {
+		setRuntimeProfiles(hp, hp.rates, hp.suspended.active(hp.config.ProfileTypes), hp.reduced)
+	}
+	return nil
+}
+
+// ReduceSampling switches the mutex and block profiles between the reduced and the configured sampling rates
+func (hp *HTTPProfiler) ReduceSampling(ctx context.Context, reduced bool) error {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	hp.reduced = reduced
+	if hp.state.Is(core.StateRunning) {
+		setRuntimeProfiles(hp, hp.rates, hp.suspended.active(hp.config.ProfileTypes), reduced)
+	}
+	return nil
+}
+
+// Overhead returns the time spent serving profiles and the bytes served
+func (hp *HTTPProfiler) Overhead() core.OverheadStats {
+	return hp.overhead.stats()
+}
+
+// accounted records the bytes served and the time spent serving profiles. The CPU profile
+// and the execution trace mostly wait for their duration, so only their bytes are counted.
+func (hp *HTTPProfiler) accounted(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		cw := &countingResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(cw, r)
+
+		hp.overhead.written.Add(cw.written)
+		switch r.URL.Path {
+		case "/debug/pprof/profile", "/debug/pprof/trace":
+		default:
+			hp.overhead.track(start)
+		}
+	})
+}
+
+// profileCPU serves the CPU profile like pprof.Profile, sampling at the configured rate.
+// It is refused when ProfileTypes leaves out the CPU profile, e.g. because another backend collects it.
+func (hp *HTTPProfiler) profileCPU(w http.ResponseWriter, r *http.Request) {
+	if len(hp.config.ProfileTypes) > 0 && !slices.Contains(hp.config.ProfileTypes, core.ProfileCPU) {
+		http.Error(w, "CPU profile not collected by this backend", http.StatusNotFound)
+		return
+	}
+	if hp.rates.cpuHz == defaultCPUProfileRate {
+		pprof.Profile(w, r)
+		return
+	}
+
+	seconds, err := strconv.Atoi(r.FormValue("seconds"))
+	if err != nil || seconds <= 0 {
+		seconds = 30
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+
+	if err := startCPUProfile(w, hp.rates.cpuHz); err != nil {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "Could not enable CPU profiling: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer runtimepprof.StopCPUProfile()
+
+	select {
+	case <-time.After(time.Duration(seconds) * time.Second):
+	case <-r.Context().Done():
+	}
+}
+
+// unlessSuspended serves a profile endpoint unless all the configured profile types it backs are suspended
+func (hp *HTTPProfiler) unlessSuspended(handler http.HandlerFunc, types ...core.ProfileType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hp.suspendMu.RLock()
+		suspended := false
+		for _, pt := range types {
+			if !slices.Contains(hp.config.ProfileTypes, pt) {
+				continue
+			}
+			if suspended = hp.suspended[pt]; !suspended {
+				break
+			}
+		}
+		hp.suspendMu.RUnlock()
+
+		if suspended {
+			http.Error(w, "profile suspended by profilego", http.StatusServiceUnavailable)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// IsRunning returns the current state of the profiler
+func (hp *HTTPProfiler) IsRunning() bool {
+	return hp.state.Is(core.StateRunning)
+}
+
+// State returns the lifecycle state of the profiler
+func (hp *HTTPProfiler) State() core.State {
+	return hp.state.State()
+}
+
+// StateMachine returns the state machine tracking the profiler's lifecycle
+func (hp *HTTPProfiler) StateMachine() *core.StateMachine {
+	return hp.state
+}
+
+// shutdown stops the server, if serving, and the runtime sampling it enabled
+// Must be called with hp.mu held
+func (hp *HTTPProfiler) shutdown(ctx context.Context) error {
+	if hp.server == nil {
+		return nil
+	}
+
+	err := hp.server.Shutdown(ctx)
+	if err != nil && ctx.Err() != nil {
+		err = &core.TimeoutError{Name: hp.Name(), Operation: "shutdown", Err: err}
+	}
+
+	// Shutdown misses the listener if Serve has not picked it up yet
+	if closeErr := hp.listener.Close(); closeErr != nil && !errors.Is(closeErr, net.ErrClosed) {
+		err = errors.Join(err, closeErr)
+	}
+	setRuntimeProfiles(hp, hp.rates, nil, false)
+
+	hp.server = nil
+	hp.listener = nil
+	return err
+}
+
+// Addr returns the address the server listens on, or an empty string when it is not running.
+// It is useful when ServerAddress uses port 0.
+func (hp *HTTPProfiler) Addr() string {
+	hp.mu.RLock()
+	defer hp.mu.RUnlock()
+
+	if hp.listener == nil {
+		return ""
+	}
+	return hp.listener.Addr().String()
+}
@@ -0,0 +1,166 @@
+package profiler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+func TestHTTPProfilerServesPprof(t *testing.T) {
+	hp, err := NewHTTPProfiler(config.Config{
+		ServerAddress: "127.0.0.1:0",
+		ProfileTypes:  []core.ProfileType{core.ProfileGoroutines},
+	})
+	if err != nil {
+		t.Fatalf("NewHTTPProfiler returned error: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := hp.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	defer hp.Stop(ctx)
+
+	if !hp.IsRunning() {
+		t.Error("Profiler should be running after Start()")
+	}
+
+	addr := hp.Addr()
+	resp, err := http.Get("http://" + addr + "/debug/pprof/goroutine?debug=1")
+	if err != nil {
+		t.Fatalf("GET goroutine profile failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "goroutine profile") {
+		t.Errorf("Unexpected response %d: %.100s", resp.StatusCode, body)
+	}
+	if written := hp.Overhead().BytesWritten; written != int64(len(body)) {
+		t.Errorf("Expected %d bytes served to be accounted, got %d", len(body), written)
+	}
+
+	if err := hp.Stop(ctx); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+	if hp.IsRunning() || hp.Addr() != "" {
+		t.Error("Profiler should not be running after Stop()")
+	}
+	if _, err := http.Get("http://" + addr + "/debug/pprof/"); err == nil {
+		t.Error("Server should not accept connections after Stop()")
+	}
+
+	// Stop releases the address so the profiler can be restarted
+	if err := hp.Resume(ctx); err != nil {
+		t.Fatalf("Resume returned error: %v", err)
+	}
+}
+
+func TestNewHTTPProfilerRequiresAddress(t *testing.T) {
+	if _, err := NewHTTPProfiler(config.Config{}); err == nil {
+		t.Error("NewHTTPProfiler should fail without a listen address")
+	}
+}
+
+func TestHTTPProfilerSuspendProfileTypes(t *testing.T) {
+	hp, err := NewHTTPProfiler(config.Config{
+		ServerAddress: "127.0.0.1:0",
+		ProfileTypes:  []core.ProfileType{core.ProfileCPU, core.ProfileBlockCount, core.ProfileBlockDuration},
+	})
+	if err != nil {
+		t.Fatalf("NewHTTPProfiler returned error: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := hp.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	defer hp.Stop(ctx)
+
+	// status returns the status code of an endpoint
+	status := func(path string) int {
+		t.Helper()
+		resp, err := http.Get("http://" + hp.Addr() + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	hp.SuspendProfileTypes(ctx, []core.ProfileType{core.ProfileCPU, core.ProfileBlockCount, core.ProfileBlockDuration})
+	if code := status("/debug/pprof/profile?seconds=1"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 for a suspended CPU profile, got %d", code)
+	}
+	if code := status("/debug/pprof/block"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 for a suspended block profile, got %d", code)
+	}
+
+	hp.ResumeProfileTypes(ctx, []core.ProfileType{core.ProfileBlockCount, core.ProfileBlockDuration})
+	if code := status("/debug/pprof/block"); code != http.StatusOK {
+		t.Errorf("Expected 200 for a resumed block profile, got %d", code)
+	}
+}
+
+func TestHTTPProfilerSuspendsEveryProfileEndpoint(t *testing.T) {
+	types := []core.ProfileType{
+		core.ProfileInuseObjects, core.ProfileInuseSpace, core.ProfileAllocObjects, core.ProfileAllocSpace,
+		core.ProfileGoroutines, core.ProfileMutexCount, core.ProfileMutexDuration,
+	}
+	hp, err := NewHTTPProfiler(config.Config{ServerAddress: "127.0.0.1:0", ProfileTypes: types})
+	if err != nil {
+		t.Fatalf("NewHTTPProfiler returned error: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := hp.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	defer hp.Stop(ctx)
+
+	if hp.server.ReadHeaderTimeout != config.DefaultConfig.Timeout {
+		t.Errorf("Expected ReadHeaderTimeout %v, got %v", config.DefaultConfig.Timeout, hp.server.ReadHeaderTimeout)
+	}
+
+	hp.SuspendProfileTypes(ctx, types)
+	for _, path := range []string{"/debug/pprof/heap", "/debug/pprof/allocs", "/debug/pprof/goroutine", "/debug/pprof/mutex"} {
+		resp, err := http.Get("http://" + hp.Addr() + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503 for suspended %s, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestHTTPProfilerCPUProfileNotCollected(t *testing.T) {
+	hp, err := NewHTTPProfiler(config.Config{
+		ServerAddress: "127.0.0.1:0",
+		ProfileTypes:  []core.ProfileType{core.ProfileGoroutines},
+	})
+	if err != nil {
+		t.Fatalf("NewHTTPProfiler returned error: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := hp.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	defer hp.Stop(ctx)
+
+	resp, err := http.Get("http://" + hp.Addr() + "/debug/pprof/profile?seconds=1")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for a CPU profile left to another backend, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,117 @@
+package profiler
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wasilak/profilego/core"
+)
+
+// overhead accumulates the cost of profiling attributed to a profiler.
+// It is safe for concurrent use.
+type overhead struct {
+	cpuTime  atomic.Int64 // nanoseconds
+	buffered atomic.Int64
+	written  atomic.Int64
+	uploaded atomic.Int64
+	uploads  atomic.Int64
+}
+
+// stats returns a snapshot of the accumulated overhead
+func (o *overhead) stats() core.OverheadStats {
+	return core.OverheadStats{
+		CPUTime:       time.Duration(o.cpuTime.Load()),
+		BytesBuffered: o.buffered.Load(),
+		BytesWritten:  o.written.Load(),
+		BytesUploaded: o.uploaded.Load(),
+		Uploads:       o.uploads.Load(),
+	}
+}
+
+// track adds the time elapsed since start to the CPU time, e.g. deferred around encoding a profile
+func (o *overhead) track(start time.Time) {
+	o.cpuTime.Add(int64(time.Since(start)))
+}
+
+// trackFile adds the size of a written profile file to the bytes written
+func (o *overhead) trackFile(f *os.File) {
+	if info, err := f.Stat(); err == nil {
+		o.written.Add(info.Size())
+	}
+}
+
+// countingTransport counts the upload requests sent through it, their bytes and the time
+// spent until each request body has been sent
+type countingTransport struct {
+	base     http.RoundTripper
+	overhead *overhead
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.overhead.uploads.Add(1)
+	if req.Body == nil || req.Body == http.NoBody {
+		return t.base.RoundTrip(req)
+	}
+
+	// The request body is buffered until the transport has sent it
+	size := max(req.ContentLength, 0)
+	t.overhead.buffered.Add(size)
+
+	req = req.Clone(req.Context())
+	req.Body = &countingBody{ReadCloser: req.Body, overhead: t.overhead, size: size, start: time.Now()}
+	return t.base.RoundTrip(req)
+}
+
+// countingBody counts the bytes read from a request body and, once it is fully read or closed,
+// records the time spent sending it
+type countingBody struct {
+	io.ReadCloser
+	overhead *overhead
+	size     int64
+	start    time.Time
+	once     sync.Once
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.overhead.uploaded.Add(int64(n))
+	if err == io.EOF {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	b.done()
+	return b.ReadCloser.Close()
+}
+
+// done records the upload once
+func (b *countingBody) done() {
+	b.once.Do(func() {
+		b.overhead.track(b.start)
+		b.overhead.buffered.Add(-b.size)
+	})
+}
+
+// countingResponseWriter counts the bytes of a response
+type countingResponseWriter struct {
+	http.ResponseWriter
+	written int64
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *countingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,74 @@
+package profiler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+func TestCountingTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer server.Close()
+
+	var o overhead
+	client := &http.Client{Transport: &countingTransport{base: http.DefaultTransport, overhead: &o}}
+
+	payload := strings.Repeat("profile", 100)
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post(server.URL, "application/octet-stream", strings.NewReader(payload))
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	stats := o.stats()
+	if stats.Uploads != 2 || stats.BytesUploaded != int64(2*len(payload)) {
+		t.Errorf("Expected 2 uploads of %d bytes, got %+v", len(payload), stats)
+	}
+	if stats.BytesBuffered != 0 {
+		t.Errorf("Expected nothing buffered after the uploads, got %d bytes", stats.BytesBuffered)
+	}
+	if stats.CPUTime <= 0 {
+		t.Error("Expected the time spent sending to be accounted")
+	}
+}
+
+func TestPprofProfilerOverhead(t *testing.T) {
+	profiler, err := NewPprofProfiler(config.Config{
+		ApplicationName:  "test-app",
+		OutputDir:        t.TempDir(),
+		ProfileTypes:     []core.ProfileType{core.ProfileCPU, core.ProfileGoroutines},
+		MemoryLimitMB:    1024,
+		SnapshotInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewPprofProfiler returned error: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := profiler.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if err := profiler.ReduceSampling(ctx, true); err != nil {
+		t.Fatalf("ReduceSampling returned error: %v", err)
+	}
+	if err := profiler.Stop(ctx); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+
+	// The final CPU window and goroutine snapshot are written on Stop
+	stats := profiler.Overhead()
+	if stats.BytesWritten <= 0 || stats.CPUTime <= 0 {
+		t.Errorf("Expected the files written on Stop to be accounted, got %+v", stats)
+	}
+}
@@ -2,25 +2,35 @@ package profiler
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
-	"runtime"
 	"runtime/pprof"
+	"slices"
 	"sync"
 	"time"
 
 	"dario.cat/mergo"
 	"github.com/wasilak/profilego/config"
 	"github.com/wasilak/profilego/core"
-	"github.com/wasilak/profilego/internal/memory"
+	"github.com/wasilak/profilego/memory"
 )
 
 // PprofProfiler implements the Profiler interface for pprof
 type PprofProfiler struct {
-	mu      sync.RWMutex
-	config  config.Config
-	running bool
-	stopCh  chan struct{}
+	mu        sync.RWMutex
+	config    config.Config
+	state     *core.StateMachine
+	suspended suspension
+	rates     samplingRates
+	reduced   bool // sampling reduced to stay within the overhead budget
+	overhead  overhead
+	stopCh    chan struct{}
+	cpuFile   *os.File
+	files     filenameTemplate
+	janitor   janitor
+	seq       uint64
 }
 
 // NewPprofProfiler creates a new pprof profiler
@@ -34,9 +44,21 @@ func NewPprofProfiler(cfg config.Config) (*PprofProfiler, error) {
 		return nil, err
 	}
 
+	files := newFilenameTemplate(finalConfig.OutputDir, finalConfig.FilenameTemplate, finalConfig.ApplicationName)
+
 	pp := &PprofProfiler{
-		config: finalConfig,
-		stopCh: make(chan struct{}),
+		config:    finalConfig,
+		state:     core.NewStateMachine("pprof"),
+		suspended: make(suspension),
+		rates:     newSamplingRates(finalConfig),
+		files:     files,
+		janitor: janitor{
+			files:    files,
+			types:    append([]string{string(core.ProfileCPU)}, snapshotProfiles(finalConfig.ProfileTypes)...),
+			maxFiles: finalConfig.RetentionMaxFiles,
+			maxAge:   finalConfig.RetentionMaxAge,
+			maxBytes: finalConfig.RetentionMaxBytes,
+		},
 	}
 
 	return pp, nil
@@ -52,44 +74,45 @@ func (pp *PprofProfiler) Start(ctx context.Context) error {
 	pp.mu.Lock()
 	defer pp.mu.Unlock()
 
-	if pp.running {
+	if pp.state.Is(core.StateRunning) {
 		return nil // Already running
 	}
+	if err := pp.state.Transition(core.StateStarting); err != nil {
+		return err
+	}
 
 	// Check memory limit before starting
-	if pp.config.MemoryLimitMB > 0 {
-		if err := memory.CheckMemoryLimit(pp.config.MemoryLimitMB); err != nil {
-			return err
+	if err := memory.FromConfig(pp.config).Check(); err != nil {
+		return pp.state.Fail(err)
+	}
+
+	if pp.config.OutputDir != "" {
+		if err := os.MkdirAll(pp.config.OutputDir, 0o755); err != nil {
+			return pp.state.Fail(err)
 		}
 	}
 
+	pp.seq++
+
 	// Start profiling based on configured profile types
-	for _, profileType := range pp.config.ProfileTypes {
-		switch profileType {
-		case core.ProfileCPU:
-			f, err := os.Create(pp.config.ApplicationName + "_cpu.pprof")
-			if err != nil {
-				return err
-			}
-			if err := pprof.StartCPUProfile(f); err != nil {
-				return err
-			}
-		case core.ProfileGoroutines:
-			// Goroutine profiling is ongoing, no need to start
-			slog.DebugContext(ctx, "Goroutine profiling is ongoing")
-		case core.ProfileMutexCount, core.ProfileMutexDuration:
-			runtime.SetMutexProfileFraction(1) // Enable mutex profiling
-		case core.ProfileBlockCount, core.ProfileBlockDuration:
-			runtime.SetBlockProfileRate(1) // Enable block profiling
+	if pp.profilesCPU() {
+		if err := pp.startCPUWindow(time.Now()); err != nil {
+			return pp.state.Fail(err)
 		}
 	}
+	setRuntimeProfiles(pp, pp.rates, pp.profileTypes(), pp.reduced)
 
-	pp.running = true
+	pp.stopCh = make(chan struct{})
 
-	// Start a goroutine to periodically profile if needed
-	go pp.profileLoop()
+	// Start a goroutine to periodically write snapshots and rotate the CPU profile
+	go pp.profileLoop(pp.stopCh)
 
-	return nil
+	// Start a goroutine pruning old profiles, including those left by previous runs
+	if pp.janitor.enabled() {
+		go pp.janitorLoop(pp.stopCh)
+	}
+
+	return pp.state.Transition(core.StateRunning)
 }
 
 // Stop gracefully stops profiling
@@ -97,27 +120,32 @@ func (pp *PprofProfiler) Stop(ctx context.Context) error {
 	pp.mu.Lock()
 	defer pp.mu.Unlock()
 
-	if !pp.running {
+	if pp.state.Is(core.StateInitialized, core.StateStopped) {
 		return nil
 	}
+	wasRunning := pp.state.Is(core.StateRunning)
+	if err := pp.state.Transition(core.StateStopping); err != nil {
+		return err
+	}
 
-	// Stop profiling based on configured profile types
-	for _, profileType := range pp.config.ProfileTypes {
-		switch profileType {
-		case core.ProfileCPU:
-			pprof.StopCPUProfile()
-		case core.ProfileMutexCount, core.ProfileMutexDuration:
-			runtime.SetMutexProfileFraction(0) // Disable mutex profiling
-		case core.ProfileBlockCount, core.ProfileBlockDuration:
-			runtime.SetBlockProfileRate(0) // Disable block profiling
-		}
+	var err error
+	if wasRunning {
+		// Notify the profile loop to stop
+		close(pp.stopCh)
+
+		// Persist whatever was collected since the last tick
+		start := time.Now()
+		err = errors.Join(pp.stopCPUWindow(), pp.writeSnapshots(start))
+		pp.overhead.track(start)
 	}
 
-	// Notify the profile loop to stop
-	close(pp.stopCh)
+	// Restore the sampling rates in effect before profiling started
+	setRuntimeProfiles(pp, pp.rates, nil, false)
 
-	pp.running = false
-	return nil
+	if err != nil {
+		return pp.state.Fail(err)
+	}
+	return pp.state.Transition(core.StateStopped)
 }
 
 // Pause temporarily stops profiling
@@ -125,18 +153,22 @@ func (pp *PprofProfiler) Pause(ctx context.Context) error {
 	pp.mu.Lock()
 	defer pp.mu.Unlock()
 
-	if !pp.running {
+	if pp.state.Is(core.StatePaused) {
 		return nil
 	}
-
-	// Stop only CPU profiling during pause
-	for _, profileType := range pp.config.ProfileTypes {
-		if profileType == core.ProfileCPU {
-			pprof.StopCPUProfile()
-		}
+	if err := pp.state.Transition(core.StatePaused); err != nil {
+		return err
 	}
 
-	pp.running = false
+	// Stop the snapshot loop and close the current CPU window during pause
+	close(pp.stopCh)
+
+	// Restore the sampling rates in effect before profiling started; Resume applies them again
+	setRuntimeProfiles(pp, pp.rates, nil, false)
+
+	if err := pp.stopCPUWindow(); err != nil {
+		return pp.state.Fail(err)
+	}
 	return nil
 }
 
@@ -145,26 +177,247 @@ func (pp *PprofProfiler) Resume(ctx context.Context) error {
 	return pp.Start(ctx)
 }
 
+// SuspendProfileTypes stops collecting the given profile types until they are resumed.
+// A suspended CPU profile closes the current window; suspended snapshots are no longer written.
+func (pp *PprofProfiler) SuspendProfileTypes(ctx context.Context, types []core.ProfileType) error {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+
+	changed := pp.suspended.set(pp.config.ProfileTypes, types, true)
+	if !pp.state.Is(core.StateRunning) {
+		return nil
+	}
+
+	setRuntimeProfiles(pp, pp.rates, pp.profileTypes(), pp.reduced)
+
+	if slices.Contains(changed, core.ProfileCPU) {
+		return pp.stopCPUWindow()
+	}
+	return nil
+}
+
+// ResumeProfileTypes resumes collecting suspended profile types
+func (pp *PprofProfiler) ResumeProfileTypes(ctx context.Context, types []core.ProfileType) error {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+
+	changed := pp.suspended.set(pp.config.ProfileTypes, types, false)
+	if !pp.state.Is(core.StateRunning) {
+		return nil
+	}
+
+	setRuntimeProfiles(pp, pp.rates, pp.profileTypes(), pp.reduced)
+
+	if slices.Contains(changed, core.ProfileCPU) {
+		return pp.startCPUWindow(time.Now())
+	}
+	return nil
+}
+
+// ReduceSampling switches the mutex and block profiles between the reduced and the configured sampling rates
+func (pp *PprofProfiler) ReduceSampling(ctx context.Context, reduced bool) error {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+
+	pp.reduced = reduced
+	if pp.state.Is(core.StateRunning) {
+		setRuntimeProfiles(pp, pp.rates, pp.profileTypes(), reduced)
+	}
+	return nil
+}
+
+// Overhead returns the time spent encoding and writing profiles and the bytes written
+func (pp *PprofProfiler) Overhead() core.OverheadStats {
+	return pp.overhead.stats()
+}
+
 // IsRunning returns the current state of the profiler
 func (pp *PprofProfiler) IsRunning() bool {
-	pp.mu.RLock()
-	defer pp.mu.RUnlock()
-	return pp.running
+	return pp.state.Is(core.StateRunning)
 }
 
-// profileLoop runs profiling operations in a loop
-func (pp *PprofProfiler) profileLoop() {
-	ticker := time.NewTicker(10 * time.Second) // Profile every 10 seconds
+// State returns the lifecycle state of the profiler
+func (pp *PprofProfiler) State() core.State {
+	return pp.state.State()
+}
+
+// StateMachine returns the state machine tracking the profiler's lifecycle
+func (pp *PprofProfiler) StateMachine() *core.StateMachine {
+	return pp.state
+}
+
+// profileLoop writes a snapshot of every configured profile type on each tick
+func (pp *PprofProfiler) profileLoop(stopCh chan struct{}) {
+	ticker := time.NewTicker(pp.config.SnapshotInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			// Perform periodic profiling tasks if needed
-			// For pprof, most profiling is done through runtime
-		case <-pp.stopCh:
+		case now := <-ticker.C:
+			if err := pp.rotate(stopCh, now); err != nil {
+				slog.Error("profilego - failed to write pprof snapshot", "error", err)
+			}
+		case <-stopCh:
 			// Stop the profiling loop
 			return
 		}
 	}
 }
+
+// rotate closes the current CPU window, opens the next one and writes snapshots
+func (pp *PprofProfiler) rotate(stopCh chan struct{}, now time.Time) error {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+
+	// Stop or Pause may have won the race for the lock
+	select {
+	case <-stopCh:
+		return nil
+	default:
+	}
+
+	defer pp.overhead.track(now)
+
+	// Snapshots share the sequence number of the CPU window they close
+	errs := []error{pp.stopCPUWindow(), pp.writeSnapshots(now)}
+
+	pp.seq++
+	if pp.profilesCPU() {
+		errs = append(errs, pp.startCPUWindow(now))
+	}
+
+	return errors.Join(errs...)
+}
+
+// janitorLoop applies the retention limits on every tick
+func (pp *PprofProfiler) janitorLoop(stopCh chan struct{}) {
+	ticker := time.NewTicker(pp.config.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := pp.janitor.prune(time.Now()); err != nil {
+			slog.Error("profilego - failed to prune pprof files", "error", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// profileTypes returns the configured profile types that are not suspended
+// Must be called with pp.mu held
+func (pp *PprofProfiler) profileTypes() []core.ProfileType {
+	return pp.suspended.active(pp.config.ProfileTypes)
+}
+
+// profilesCPU returns whether CPU profiling is configured and not suspended
+// Must be called with pp.mu held
+func (pp *PprofProfiler) profilesCPU() bool {
+	return slices.Contains(pp.profileTypes(), core.ProfileCPU)
+}
+
+// startCPUWindow starts writing the CPU profile into a new file
+// Must be called with pp.mu held
+func (pp *PprofProfiler) startCPUWindow(now time.Time) error {
+	f, err := os.Create(pp.fileName(string(core.ProfileCPU), now))
+	if err != nil {
+		return err
+	}
+	if err := startCPUProfile(f, pp.rates.cpuHz); err != nil {
+		f.Close()
+		return err
+	}
+
+	pp.cpuFile = f
+	return nil
+}
+
+// stopCPUWindow stops the CPU profile and closes its file
+// Must be called with pp.mu held
+func (pp *PprofProfiler) stopCPUWindow() error {
+	if pp.cpuFile == nil {
+		return nil
+	}
+
+	pprof.StopCPUProfile()
+	pp.overhead.trackFile(pp.cpuFile)
+	err := pp.cpuFile.Close()
+	pp.cpuFile = nil
+	return err
+}
+
+// writeSnapshots writes one file per runtime profile backing the configured profile types
+// Must be called with pp.mu held
+func (pp *PprofProfiler) writeSnapshots(now time.Time) error {
+	var errs []error
+	for _, name := range snapshotProfiles(pp.profileTypes()) {
+		if err := pp.writeSnapshot(name, now); err != nil {
+			errs = append(errs, fmt.Errorf("%s snapshot: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeSnapshot writes the named runtime profile to its own file
+func (pp *PprofProfiler) writeSnapshot(name string, now time.Time) error {
+	profile := pprof.Lookup(name)
+	if profile == nil {
+		return fmt.Errorf("unknown runtime profile %q", name)
+	}
+
+	f, err := os.Create(pp.fileName(name, now))
+	if err != nil {
+		return err
+	}
+
+	if err := profile.WriteTo(f, 0); err != nil {
+		f.Close()
+		return err
+	}
+	pp.overhead.trackFile(f)
+	return f.Close()
+}
+
+// fileName returns the path for a profile written at the given time
+func (pp *PprofProfiler) fileName(profile string, now time.Time) string {
+	return pp.files.path(profile, now, pp.seq)
+}
+
+// snapshotProfiles returns the runtime/pprof profile names backing the given profile types.
+// Several profile types share a runtime profile (e.g. alloc_objects and alloc_space),
+// so each name is returned only once, in the order it is first needed.
+func snapshotProfiles(profileTypes []core.ProfileType) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, pt := range profileTypes {
+		name, ok := snapshotProfileName(pt)
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// snapshotProfileName maps a profile type to its runtime/pprof profile name.
+// CPU is not a snapshot profile and is handled by the CPU window instead.
+func snapshotProfileName(pt core.ProfileType) (string, bool) {
+	switch pt {
+	case core.ProfileAllocObjects, core.ProfileAllocSpace:
+		return "allocs", true
+	case core.ProfileInuseObjects, core.ProfileInuseSpace:
+		return "heap", true
+	case core.ProfileGoroutines:
+		return "goroutine", true
+	case core.ProfileMutexCount, core.ProfileMutexDuration:
+		return "mutex", true
+	case core.ProfileBlockCount, core.ProfileBlockDuration:
+		return "block", true
+	default:
+		return "", false
+	}
+}
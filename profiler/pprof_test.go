@@ -1,7 +1,15 @@
 package profiler
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/wasilak/profilego/config"
 	"github.com/wasilak/profilego/core"
@@ -46,5 +54,202 @@ func TestPprofProfilerIsRunning(t *testing.T) {
 	}
 }
 
+func TestSnapshotProfiles(t *testing.T) {
+	types := []core.ProfileType{
+		core.ProfileCPU,
+		core.ProfileInuseSpace,
+		core.ProfileAllocObjects,
+		core.ProfileAllocSpace,
+		core.ProfileInuseObjects,
+		core.ProfileGoroutines,
+		core.ProfileMutexCount,
+		core.ProfileMutexDuration,
+		core.ProfileBlockCount,
+		core.ProfileBlockDuration,
+	}
+
+	expected := []string{"heap", "allocs", "goroutine", "mutex", "block"}
+	if got := snapshotProfiles(types); !reflect.DeepEqual(got, expected) {
+		t.Errorf("snapshotProfiles() = %v, want %v", got, expected)
+	}
+}
+
+func TestPprofProfilerWritesSnapshots(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg := config.Config{
+		ApplicationName:  "test-app",
+		OutputDir:        filepath.Join(dir, "profiles"),
+		Backend:          core.PprofBackend,
+		ProfileTypes:     []core.ProfileType{core.ProfileCPU, core.ProfileInuseSpace, core.ProfileGoroutines},
+		MemoryLimitMB:    1024,
+		SnapshotInterval: 50 * time.Millisecond,
+	}
+	profiler, err := NewPprofProfiler(cfg)
+	if err != nil {
+		t.Fatalf("NewPprofProfiler returned error: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := profiler.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	time.Sleep(175 * time.Millisecond)
+	if err := profiler.Stop(ctx); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+
+	for pattern, want := range map[string]int{
+		"test-app_cpu_*.pprof":       2,
+		"test-app_heap_*.pprof":      2,
+		"test-app_goroutine_*.pprof": 2,
+	} {
+		matches, _ := filepath.Glob(filepath.Join(dir, "profiles", pattern))
+		if len(matches) < want {
+			t.Errorf("Expected at least %d files matching %s, got %d", want, pattern, len(matches))
+		}
+	}
+}
+
 // Note: We don't test Start/Stop/Pause/Resume extensively since they interact with runtime profiling
 // and would require complex setup to test properly. The structure and basic functionality is tested.
+
+func TestPprofProfilerLifecycleStates(t *testing.T) {
+	profiler, err := NewPprofProfiler(config.Config{
+		ApplicationName: "test-app",
+		ProfileTypes:    []core.ProfileType{core.ProfileGoroutines},
+		OutputDir:       t.TempDir(),
+		MemoryLimitMB:   1024,
+	})
+	if err != nil {
+		t.Fatalf("NewPprofProfiler returned error: %v", err)
+	}
+	ctx := context.Background()
+
+	var transitionErr *core.TransitionError
+	if err := profiler.Pause(ctx); !errors.As(err, &transitionErr) {
+		t.Errorf("Pause before Start should return *core.TransitionError, got %v", err)
+	}
+
+	steps := []struct {
+		op   func(context.Context) error
+		want core.State
+	}{
+		{profiler.Start, core.StateRunning},
+		{profiler.Pause, core.StatePaused},
+		{profiler.Resume, core.StateRunning},
+		{profiler.Stop, core.StateStopped},
+		{profiler.Stop, core.StateStopped},
+		{profiler.Start, core.StateRunning},
+		{profiler.Pause, core.StatePaused},
+		{profiler.Stop, core.StateStopped},
+	}
+	for i, step := range steps {
+		if err := step.op(ctx); err != nil {
+			t.Fatalf("Step %d returned error: %v", i, err)
+		}
+		if state := profiler.State(); state != step.want {
+			t.Fatalf("Step %d: expected %s, got %s", i, step.want, state)
+		}
+	}
+}
+
+func TestPprofProfilerSuspendProfileTypes(t *testing.T) {
+	dir := t.TempDir()
+
+	profiler, err := NewPprofProfiler(config.Config{
+		ApplicationName:  "test-app",
+		OutputDir:        dir,
+		ProfileTypes:     []core.ProfileType{core.ProfileCPU, core.ProfileAllocSpace, core.ProfileGoroutines},
+		MemoryLimitMB:    1024,
+		SnapshotInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewPprofProfiler returned error: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := profiler.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	defer profiler.Stop(ctx)
+
+	if err := profiler.SuspendProfileTypes(ctx, []core.ProfileType{core.ProfileCPU, core.ProfileAllocSpace}); err != nil {
+		t.Fatalf("SuspendProfileTypes returned error: %v", err)
+	}
+	if profiler.cpuFile != nil {
+		t.Error("Suspending CPU should close the CPU window")
+	}
+	if types := profiler.profileTypes(); !slices.Equal(types, []core.ProfileType{core.ProfileGoroutines}) {
+		t.Errorf("Expected only goroutines to be collected, got %v", types)
+	}
+
+	if err := profiler.ResumeProfileTypes(ctx, []core.ProfileType{core.ProfileCPU}); err != nil {
+		t.Fatalf("ResumeProfileTypes returned error: %v", err)
+	}
+	if profiler.cpuFile == nil {
+		t.Error("Resuming CPU should open a new CPU window")
+	}
+	if err := profiler.Stop(ctx); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+
+	// The allocs snapshot is skipped while alloc profiles are suspended
+	if matches, _ := filepath.Glob(filepath.Join(dir, "test-app_allocs_*.pprof")); len(matches) != 0 {
+		t.Errorf("Expected no allocs snapshots, got %v", matches)
+	}
+}
+
+func TestPprofProfilerSharesCPUWithPyroscope(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := config.Config{
+		ApplicationName:  "test-app",
+		ServerAddress:    server.URL,
+		ProfileTypes:     []core.ProfileType{core.ProfileCPU, core.ProfileGoroutines},
+		OutputDir:        dir,
+		MemoryLimitMB:    1024,
+		SnapshotInterval: time.Hour,
+		Backends: []config.BackendConfig{
+			{Type: core.PyroscopeBackend},
+			{Type: core.PprofBackend},
+		},
+	}
+
+	ctx := context.Background()
+	var profilers []core.Profiler
+	for _, b := range cfg.EffectiveBackends() {
+		var profiler core.Profiler
+		var err error
+		if b.Type == core.PyroscopeBackend {
+			profiler, err = NewPyroscopeProfiler(b.Config)
+		} else {
+			profiler, err = NewPprofProfiler(b.Config)
+		}
+		if err != nil {
+			t.Fatalf("Creating %s returned error: %v", b.Name, err)
+		}
+		if err := profiler.Start(ctx); err != nil {
+			t.Fatalf("Starting %s returned error: %v", b.Name, err)
+		}
+		profilers = append(profilers, profiler)
+
+		// Pyroscope starts its CPU profile in the background, give it the chance to take it first
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	for deadline := time.Now().Add(300 * time.Millisecond); time.Now().Before(deadline); {
+	}
+
+	for _, profiler := range profilers {
+		if err := profiler.Stop(ctx); err != nil {
+			t.Fatalf("Stopping %s returned error: %v", profiler.Name(), err)
+		}
+	}
+
+	if samples := profileLabels(t, dir, "cpu"); len(samples) == 0 {
+		t.Error("Expected the pprof CPU profile to contain samples while Pyroscope runs alongside")
+	}
+}
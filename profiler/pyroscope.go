@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/url"
 	"strings"
 	"sync"
@@ -12,7 +13,7 @@ import (
 
 	"github.com/wasilak/profilego/config"
 	"github.com/wasilak/profilego/core"
-	"github.com/wasilak/profilego/internal/memory"
+	"github.com/wasilak/profilego/memory"
 )
 
 // pyroscopeLogger implements the logging interface required by pyroscope library
@@ -60,27 +61,28 @@ func (l pyroscopeLogger) handleMessage(msg string) string {
 
 // formatServerAddressAsURL formats a server address as a proper URL
 // If the address is already a valid URL, it returns it as-is
-// If the address is just a host:port, it adds the http:// scheme
-func formatServerAddressAsURL(serverAddress string) (string, error) {
+// If the address is just a host:port, it adds the https:// scheme when secure is set
+// and the http:// scheme otherwise
+func formatServerAddressAsURL(serverAddress string, secure bool) (string, error) {
 	// First, try to parse as URL to see if it's already valid
+	// A bare host:port parses with the host as scheme, so require the "://" separator
 	parsedURL, err := url.Parse(serverAddress)
-	if err == nil && parsedURL.Scheme != "" {
+	if err == nil && parsedURL.Scheme != "" && strings.Contains(serverAddress, "://") {
 		// Already a valid URL
 		return serverAddress, nil
 	}
 
-	// If parsing failed or no scheme, try to add http:// scheme
-	httpURL := "http://" + serverAddress
-	_, err = url.Parse(httpURL)
-	if err == nil {
-		return httpURL, nil
+	schemes := []string{"http://", "https://"}
+	if secure {
+		schemes = []string{"https://"}
 	}
 
-	// If that also fails, try https://
-	httpsURL := "https://" + serverAddress
-	_, err = url.Parse(httpsURL)
-	if err == nil {
-		return httpsURL, nil
+	// If parsing failed or no scheme, try to add a scheme
+	for _, scheme := range schemes {
+		formattedURL := scheme + serverAddress
+		if _, err := url.Parse(formattedURL); err == nil {
+			return formattedURL, nil
+		}
 	}
 
 	// If all attempts fail, return the original and let Pyroscope handle it
@@ -89,10 +91,16 @@ func formatServerAddressAsURL(serverAddress string) (string, error) {
 
 // PyroscopeProfiler implements the Profiler interface for Pyroscope
 type PyroscopeProfiler struct {
-	mu       sync.RWMutex
-	config   config.Config
-	profiler *pyroscope.Profiler
-	running  bool
+	mu          sync.RWMutex
+	config      config.Config
+	credentials credentials
+	profiler    *pyroscope.Profiler
+	state       *core.StateMachine
+	suspended   suspension
+	rates       samplingRates
+	reduced     bool // sampling reduced to stay within the overhead budget
+	overhead    overhead
+	tags        *globalTags // static tags applied to uploads, including those changed at runtime
 }
 
 // NewPyroscopeProfiler creates a new Pyroscope profiler
@@ -100,8 +108,23 @@ func NewPyroscopeProfiler(cfg config.Config) (*PyroscopeProfiler, error) {
 	// Use the config as-is (merging should be handled at the InitWithConfig level)
 	finalConfig := cfg
 
+	// Resolve credentials up front so missing secret files are reported immediately
+	creds, err := newCredentials(finalConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	pp := &PyroscopeProfiler{
-		config: finalConfig,
+		config:      finalConfig,
+		credentials: creds,
+		state:       core.NewStateMachine("pyroscope"),
+		suspended:   make(suspension),
+		rates:       newSamplingRates(finalConfig),
+		tags:        newGlobalTags(finalConfig.Tags),
+	}
+
+	if pp.rates.cpuHz != defaultCPUProfileRate {
+		slog.Warn("profilego - CPUProfileRate is not supported by the pyroscope backend, sampling at 100 Hz", "cpu_profile_rate", pp.rates.cpuHz)
 	}
 
 	return pp, nil
@@ -117,49 +140,71 @@ func (pp *PyroscopeProfiler) Start(ctx context.Context) error {
 	pp.mu.Lock()
 	defer pp.mu.Unlock()
 
-	if pp.running {
+	if pp.state.Is(core.StateRunning) {
 		return nil // Already running
 	}
+	if err := pp.state.Transition(core.StateStarting); err != nil {
+		return err
+	}
 
 	// Check memory limit before starting
-	if pp.config.MemoryLimitMB > 0 {
-		if err := memory.CheckMemoryLimit(pp.config.MemoryLimitMB); err != nil {
-			return err
-		}
+	if err := memory.FromConfig(pp.config).Check(); err != nil {
+		return pp.state.Fail(err)
 	}
 
+	if err := pp.startProfiler(); err != nil {
+		return pp.state.Fail(err)
+	}
+	setRuntimeProfiles(pp, pp.rates, pp.suspended.active(pp.config.ProfileTypes), pp.reduced)
+	return pp.state.Transition(core.StateRunning)
+}
+
+// startProfiler starts the Pyroscope profiler for the profile types that are not suspended.
+// Pyroscope falls back to its default profile types when none are given, so no profiler is
+// started when all of them are suspended.
+// Must be called with pp.mu held
+func (pp *PyroscopeProfiler) startProfiler() error {
 	// Convert profile types to pyroscope profile types
-	profileTypes := make([]pyroscope.ProfileType, 0, len(pp.config.ProfileTypes))
-	for _, pt := range pp.config.ProfileTypes {
+	active := pp.suspended.active(pp.config.ProfileTypes)
+	profileTypes := make([]pyroscope.ProfileType, 0, len(active))
+	for _, pt := range active {
 		pyroPT, ok := pp.convertProfileType(pt)
 		if ok {
 			profileTypes = append(profileTypes, pyroPT)
 		}
 	}
+	if len(profileTypes) == 0 && len(pp.suspended) > 0 {
+		return nil
+	}
 
 	// Format server address as proper URL for Pyroscope library
-	formattedServerAddress, err := formatServerAddressAsURL(pp.config.ServerAddress)
+	formattedServerAddress, err := formatServerAddressAsURL(pp.config.ServerAddress, pp.config.TLSEnabled())
 	if err != nil {
 		return fmt.Errorf("failed to format server address: %w", err)
 	}
 
+	// Build the upload client honouring the TLS settings
+	httpClient, err := newHTTPClient(pp.config, pp.credentials)
+	if err != nil {
+		return fmt.Errorf("failed to configure TLS: %w", err)
+	}
+	tags := pp.tags.load()
+	httpClient.Transport = &countingTransport{
+		base:     &globalTagsTransport{base: httpClient.Transport, started: tags, tags: pp.tags},
+		overhead: &pp.overhead,
+	}
+
 	pyroscopeConfig := pyroscope.Config{
-		Logger:          pyroscopeLogger{}, // Use logger specifically for pyroscope
-		ApplicationName: pp.config.ApplicationName,
-		ServerAddress:   formattedServerAddress,
-		Tags:            pp.config.Tags,
-		ProfileTypes:    profileTypes,
-	}
-
-	// Configure TLS if enabled
-	if pp.config.EnableTLS {
-		// Since the pyroscope library doesn't directly expose TLS config in its public API
-		// we'll provide an example of how TLS could be configured with a custom HTTP client
-		// In actual implementation, you'd need to use the appropriate pyroscope options
-		// For now, we'll just validate the TLS settings and ensure they are properly configured
-		if pp.config.SkipTLSVerify {
-			// This is a security warning in a real implementation
-		}
+		Logger:            pyroscopeLogger{}, // Use logger specifically for pyroscope
+		ApplicationName:   pp.config.ApplicationName,
+		ServerAddress:     formattedServerAddress,
+		Tags:              tags,
+		ProfileTypes:      profileTypes,
+		HTTPClient:        httpClient,
+		BasicAuthUser:     pp.credentials.basicAuthUser,
+		BasicAuthPassword: pp.credentials.basicAuthPassword,
+		TenantID:          pp.credentials.tenantID,
+		HTTPHeaders:       pp.credentials.headers,
 	}
 
 	profiler, err := pyroscope.Start(pyroscopeConfig)
@@ -168,7 +213,6 @@ func (pp *PyroscopeProfiler) Start(ctx context.Context) error {
 	}
 
 	pp.profiler = profiler
-	pp.running = true
 	return nil
 }
 
@@ -177,18 +221,22 @@ func (pp *PyroscopeProfiler) Stop(ctx context.Context) error {
 	pp.mu.Lock()
 	defer pp.mu.Unlock()
 
-	if !pp.running || pp.profiler == nil {
+	if pp.state.Is(core.StateInitialized, core.StateStopped) {
 		return nil
 	}
-
-	err := pp.profiler.Stop()
-	if err != nil {
+	if err := pp.state.Transition(core.StateStopping); err != nil {
 		return err
 	}
 
-	pp.profiler = nil
-	pp.running = false
-	return nil
+	// Restore the sampling rates in effect before profiling started
+	setRuntimeProfiles(pp, pp.rates, nil, false)
+
+	// A paused profiler has already been stopped
+	if err := pp.stopProfiler(ctx, "stop"); err != nil {
+		return pp.state.Fail(err)
+	}
+
+	return pp.state.Transition(core.StateStopped)
 }
 
 // Pause temporarily stops profiling
@@ -197,17 +245,17 @@ func (pp *PyroscopeProfiler) Pause(ctx context.Context) error {
 	pp.mu.Lock()
 	defer pp.mu.Unlock()
 
-	if !pp.running || pp.profiler == nil {
+	if pp.state.Is(core.StatePaused) {
 		return nil
 	}
-
-	err := pp.profiler.Stop()
-	if err != nil {
+	if err := pp.state.Transition(core.StatePaused); err != nil {
 		return err
 	}
 
-	pp.profiler = nil
-	pp.running = false
+	setRuntimeProfiles(pp, pp.rates, nil, false)
+	if err := pp.stopProfiler(ctx, "pause"); err != nil {
+		return pp.state.Fail(err)
+	}
 	return nil
 }
 
@@ -217,11 +265,121 @@ func (pp *PyroscopeProfiler) Resume(ctx context.Context) error {
 	return pp.Start(ctx)
 }
 
+// SuspendProfileTypes stops collecting the given profile types until they are resumed.
+// Pyroscope cannot change profile types at runtime, so a running profiler is restarted,
+// flushing the profiles collected so far.
+func (pp *PyroscopeProfiler) SuspendProfileTypes(ctx context.Context, types []core.ProfileType) error {
+	return pp.setSuspended(ctx, types, true)
+}
+
+// ResumeProfileTypes resumes collecting suspended profile types, restarting a running profiler
+func (pp *PyroscopeProfiler) ResumeProfileTypes(ctx context.Context, types []core.ProfileType) error {
+	return pp.setSuspended(ctx, types, false)
+}
+
+// setSuspended suspends or resumes profile types and restarts a running profiler if they changed
+func (pp *PyroscopeProfiler) setSuspended(ctx context.Context, types []core.ProfileType, suspended bool) error {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+
+	changed := pp.suspended.set(pp.config.ProfileTypes, types, suspended)
+	if len(changed) == 0 || !pp.state.Is(core.StateRunning) {
+		return nil
+	}
+	setRuntimeProfiles(pp, pp.rates, pp.suspended.active(pp.config.ProfileTypes), pp.reduced)
+
+	if err := pp.stopProfiler(ctx, "restart"); err != nil {
+		return pp.state.Fail(err)
+	}
+	if err := pp.startProfiler(); err != nil {
+		return pp.state.Fail(err)
+	}
+	return nil
+}
+
+// ReduceSampling switches the mutex and block profiles between the reduced and the configured sampling rates
+func (pp *PyroscopeProfiler) ReduceSampling(ctx context.Context, reduced bool) error {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+
+	pp.reduced = reduced
+	if pp.state.Is(core.StateRunning) {
+		setRuntimeProfiles(pp, pp.rates, pp.suspended.active(pp.config.ProfileTypes), reduced)
+	}
+	return nil
+}
+
+// SetGlobalTag adds or replaces a static tag. It applies to uploads from now on, including
+// those of profiles collected before the change, without restarting the profiler.
+func (pp *PyroscopeProfiler) SetGlobalTag(key, value string) error {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+
+	tags := maps.Clone(pp.tags.load())
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	tags[key] = value
+	pp.tags.store(tags)
+	return nil
+}
+
+// RemoveGlobalTag removes a static tag from uploads from now on, without restarting the profiler
+func (pp *PyroscopeProfiler) RemoveGlobalTag(key string) error {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+
+	tags := maps.Clone(pp.tags.load())
+	delete(tags, key)
+	pp.tags.store(tags)
+	return nil
+}
+
+// Overhead returns the upload requests, the bytes uploaded or waiting to be sent and the
+// time spent sending them. Collecting and encoding profiles happens inside the Pyroscope
+// library and is not included.
+func (pp *PyroscopeProfiler) Overhead() core.OverheadStats {
+	return pp.overhead.stats()
+}
+
 // IsRunning returns the current state of the profiler
 func (pp *PyroscopeProfiler) IsRunning() bool {
-	pp.mu.RLock()
-	defer pp.mu.RUnlock()
-	return pp.running
+	return pp.state.Is(core.StateRunning)
+}
+
+// State returns the lifecycle state of the profiler
+func (pp *PyroscopeProfiler) State() core.State {
+	return pp.state.State()
+}
+
+// StateMachine returns the state machine tracking the profiler's lifecycle
+func (pp *PyroscopeProfiler) StateMachine() *core.StateMachine {
+	return pp.state
+}
+
+// stopProfiler stops the Pyroscope profiler, which flushes the final profiles.
+// If ctx is done before the upload completes, a *core.TimeoutError is returned and the
+// upload is abandoned in the background.
+// Must be called with pp.mu held
+func (pp *PyroscopeProfiler) stopProfiler(ctx context.Context, operation string) error {
+	if pp.profiler == nil {
+		return nil
+	}
+
+	profiler := pp.profiler
+	pp.profiler = nil
+
+	done := make(chan error, 1)
+	go func() {
+		done <- profiler.Stop()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return &core.TimeoutError{Name: pp.Name(), Operation: operation, Err: ctx.Err()}
+	}
 }
 
 // convertProfileType converts internal profile type to pyroscope profile type
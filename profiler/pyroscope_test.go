@@ -1,7 +1,12 @@
 package profiler
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/wasilak/profilego/config"
 	"github.com/wasilak/profilego/core"
@@ -80,3 +85,41 @@ func TestConvertProfileType(t *testing.T) {
 // Note: We don't test Start/Stop/Pause/Resume extensively since they interact with external services
 // and would require mocking the pyroscope library which is complex.
 // The important logic is covered in the conversion and helper methods.
+
+func TestPyroscopeProfilerStopTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	profiler, err := NewPyroscopeProfiler(config.Config{
+		ApplicationName: "test-app",
+		Backend:         core.PyroscopeBackend,
+		ServerAddress:   server.URL,
+		ProfileTypes:    []core.ProfileType{core.ProfileCPU},
+	})
+	if err != nil {
+		t.Fatalf("NewPyroscopeProfiler returned error: %v", err)
+	}
+	if err := profiler.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = profiler.Stop(ctx)
+
+	var timeoutErr *core.TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Operation != "stop" {
+		t.Fatalf("Expected *core.TimeoutError for stop, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the error to wrap context.DeadlineExceeded, got %v", err)
+	}
+	if profiler.State() != core.StateFailed {
+		t.Errorf("Expected failed state, got %s", profiler.State())
+	}
+}
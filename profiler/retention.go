@@ -0,0 +1,113 @@
+package profiler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// janitor prunes profile files according to the configured retention limits
+type janitor struct {
+	files    filenameTemplate
+	types    []string
+	maxFiles int
+	maxAge   time.Duration
+	maxBytes int64
+}
+
+// profileFile describes a profile file found on disk
+type profileFile struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// enabled returns whether any retention limit is configured
+func (j janitor) enabled() bool {
+	return j.maxFiles > 0 || j.maxAge > 0 || j.maxBytes > 0
+}
+
+// prune removes files that are too old, exceed the per-type count or exceed the total size.
+// Newer files are always kept in favour of older ones.
+func (j janitor) prune(now time.Time) error {
+	if !j.enabled() {
+		return nil
+	}
+
+	files, err := j.list("")
+	if err != nil {
+		return err
+	}
+
+	remove := make(map[string]bool)
+
+	if j.maxAge > 0 {
+		for _, f := range files {
+			if now.Sub(f.modTime) > j.maxAge {
+				remove[f.path] = true
+			}
+		}
+	}
+
+	if j.maxFiles > 0 {
+		for _, profileType := range j.types {
+			typed, err := j.list(profileType)
+			if err != nil {
+				return err
+			}
+			for i, f := range typed {
+				if i >= j.maxFiles {
+					remove[f.path] = true
+				}
+			}
+		}
+	}
+
+	if j.maxBytes > 0 {
+		var total int64
+		for _, f := range files {
+			if remove[f.path] {
+				continue
+			}
+			total += f.size
+			if total > j.maxBytes {
+				remove[f.path] = true
+			}
+		}
+	}
+
+	var errs []error
+	for path := range remove {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// list returns the profile files of the given type (or all types), newest first
+func (j janitor) list(profileType string) ([]profileFile, error) {
+	paths, err := filepath.Glob(j.files.glob(profileType))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]profileFile, 0, len(paths))
+	for _, path := range paths {
+		if !j.files.matches(path, profileType) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, profileFile{path: path, modTime: info.ModTime(), size: info.Size()})
+	}
+
+	sort.Slice(files, func(a, b int) bool {
+		return files[a].modTime.After(files[b].modTime)
+	})
+	return files, nil
+}
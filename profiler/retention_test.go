@@ -0,0 +1,128 @@
+package profiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeProfileFile writes a profile file of the given size and modification time
+func writeProfileFile(t *testing.T, ft filenameTemplate, profileType string, seq uint64, size int, modTime time.Time) string {
+	t.Helper()
+
+	path := ft.path(profileType, modTime, seq)
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestJanitorDisabled(t *testing.T) {
+	j := janitor{}
+	if j.enabled() {
+		t.Error("Janitor without limits should be disabled")
+	}
+	if err := j.prune(time.Now()); err != nil {
+		t.Errorf("prune returned error: %v", err)
+	}
+}
+
+func TestJanitorMaxFiles(t *testing.T) {
+	ft := newFilenameTemplate(t.TempDir(), "{app}_{type}_{seq}.pprof", "test-app")
+	now := time.Now()
+
+	for i := 1; i <= 3; i++ {
+		writeProfileFile(t, ft, "cpu", uint64(i), 1, now.Add(time.Duration(i)*time.Second))
+		writeProfileFile(t, ft, "heap", uint64(i), 1, now.Add(time.Duration(i)*time.Second))
+	}
+
+	j := janitor{files: ft, types: []string{"cpu", "heap"}, maxFiles: 2}
+	if err := j.prune(now); err != nil {
+		t.Fatalf("prune returned error: %v", err)
+	}
+
+	for _, profileType := range j.types {
+		matches, _ := filepath.Glob(ft.glob(profileType))
+		if len(matches) != 2 {
+			t.Errorf("Expected 2 %s files, got %d", profileType, len(matches))
+		}
+		if _, err := os.Stat(ft.path(profileType, now, 1)); !os.IsNotExist(err) {
+			t.Errorf("Expected oldest %s file to be removed", profileType)
+		}
+	}
+}
+
+func TestJanitorMaxAge(t *testing.T) {
+	ft := newFilenameTemplate(t.TempDir(), "{app}_{type}_{seq}.pprof", "test-app")
+	now := time.Now()
+
+	old := writeProfileFile(t, ft, "cpu", 1, 1, now.Add(-2*time.Hour))
+	recent := writeProfileFile(t, ft, "cpu", 2, 1, now.Add(-time.Minute))
+
+	j := janitor{files: ft, types: []string{"cpu"}, maxAge: time.Hour}
+	if err := j.prune(now); err != nil {
+		t.Fatalf("prune returned error: %v", err)
+	}
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("Expected old file to be removed")
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Errorf("Expected recent file to be kept: %v", err)
+	}
+}
+
+func TestJanitorMaxBytes(t *testing.T) {
+	ft := newFilenameTemplate(t.TempDir(), "{app}_{type}_{seq}.pprof", "test-app")
+	now := time.Now()
+
+	oldest := writeProfileFile(t, ft, "cpu", 1, 100, now.Add(-3*time.Second))
+	middle := writeProfileFile(t, ft, "heap", 2, 100, now.Add(-2*time.Second))
+	newest := writeProfileFile(t, ft, "cpu", 3, 100, now.Add(-time.Second))
+
+	j := janitor{files: ft, types: []string{"cpu", "heap"}, maxBytes: 250}
+	if err := j.prune(now); err != nil {
+		t.Fatalf("prune returned error: %v", err)
+	}
+
+	if _, err := os.Stat(oldest); !os.IsNotExist(err) {
+		t.Error("Expected oldest file to be removed")
+	}
+	for _, path := range []string{middle, newest} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be kept: %v", path, err)
+		}
+	}
+}
+
+func TestJanitorSharedDirectory(t *testing.T) {
+	dir := t.TempDir()
+	mine := newFilenameTemplate(dir, "{app}_{type}_{timestamp}.pprof", "my-app")
+	other := newFilenameTemplate(dir, "{app}_{type}_{timestamp}.pprof", "my-app_x")
+	now := time.Now()
+
+	var otherFiles []string
+	for i := 1; i <= 3; i++ {
+		modTime := now.Add(time.Duration(i) * time.Second)
+		writeProfileFile(t, mine, "cpu", uint64(i), 1, modTime)
+		otherFiles = append(otherFiles, writeProfileFile(t, other, "cpu", uint64(i), 1, modTime))
+	}
+
+	j := janitor{files: mine, types: []string{"cpu"}, maxFiles: 1}
+	if err := j.prune(now); err != nil {
+		t.Fatalf("prune returned error: %v", err)
+	}
+
+	for _, path := range otherFiles {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected the file of another application to be kept: %v", err)
+		}
+	}
+	if files, _ := j.list("cpu"); len(files) != 1 {
+		t.Errorf("Expected 1 cpu file left, got %d", len(files))
+	}
+}
@@ -0,0 +1,192 @@
+package profiler
+
+import (
+	"io"
+	"log/slog"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+const (
+	// defaultCPUProfileRate is the rate pprof.StartCPUProfile samples at, in Hz
+	defaultCPUProfileRate = 100
+	// defaultMutexProfileFraction is used when MutexProfileFraction is not set
+	defaultMutexProfileFraction = 10
+	// defaultBlockProfileRate is used when BlockProfileRate is not set
+	defaultBlockProfileRate = 10 * time.Microsecond
+	// samplingReductionFactor divides how often mutex and block events are sampled while sampling is reduced
+	samplingReductionFactor = 10
+)
+
+// samplingRates are the runtime sampling rates of a profiler, resolved from its configuration
+type samplingRates struct {
+	cpuHz         int
+	memRate       int // 0 leaves runtime.MemProfileRate unchanged
+	mutexFraction int
+	blockRate     int // nanoseconds
+}
+
+// newSamplingRates returns the sampling rates configured in cfg, using the defaults for unset ones
+func newSamplingRates(cfg config.Config) samplingRates {
+	rates := samplingRates{
+		cpuHz:         cfg.CPUProfileRate,
+		memRate:       cfg.MemProfileRate,
+		mutexFraction: cfg.MutexProfileFraction,
+		blockRate:     int(cfg.BlockProfileRate),
+	}
+	if rates.cpuHz <= 0 {
+		rates.cpuHz = defaultCPUProfileRate
+	}
+	if rates.mutexFraction <= 0 {
+		rates.mutexFraction = defaultMutexProfileFraction
+	}
+	if rates.blockRate <= 0 {
+		rates.blockRate = int(defaultBlockProfileRate)
+	}
+	return rates
+}
+
+// customCPURate makes sure a CPU profile rate other than the default is applied only once
+var customCPURate sync.Once
+
+// startCPUProfile starts the CPU profile at the given rate. pprof.StartCPUProfile always asks for
+// 100 Hz, so another rate is set first; the runtime keeps it but prints a warning to stderr every
+// time. To keep a library from writing to stderr on every profile, the rate is only applied to the
+// first CPU profile of the process and later ones sample at 100 Hz.
+func startCPUProfile(w io.Writer, hz int) error {
+	if hz != defaultCPUProfileRate {
+		customCPURate.Do(func() {
+			slog.Warn("profilego - the Go runtime only keeps a CPU profile rate other than 100 Hz with a warning on stderr, "+
+				"applying it to the first CPU profile only", "cpu_profile_rate", hz)
+			runtime.SetCPUProfileRate(hz)
+		})
+	}
+	return pprof.StartCPUProfile(w)
+}
+
+// samplingKind is a process-wide runtime sampling setting
+type samplingKind int
+
+const (
+	mutexSampling samplingKind = iota
+	blockSampling
+	memSampling
+)
+
+// runtimeSampling coordinates the process-wide sampling rates between profilers running side by side.
+// While several profilers need a sampling, the finest rate requested is in effect; the rate in effect
+// before the first one enabled it is restored once the last one is done.
+type runtimeSampling struct {
+	mu     sync.Mutex
+	owners [3]map[any]int // rate requested by every profiler needing each sampling
+	saved  [3]int         // rates in effect before the first profiler enabled each sampling
+	// blockRate is the block profile rate last set, as the runtime cannot report it
+	blockRate int
+}
+
+var sampling = runtimeSampling{owners: [3]map[any]int{{}, {}, {}}}
+
+// enable records the rate the owner needs for a sampling and applies the finest requested rate
+func (s *runtimeSampling) enable(kind samplingKind, owner any, rate int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.owners[kind]) == 0 {
+		s.saved[kind] = s.current(kind)
+	}
+	s.owners[kind][owner] = rate
+	s.apply(kind)
+}
+
+// disable releases the owner's sampling, restoring the previous rate once no profiler needs it
+func (s *runtimeSampling) disable(kind samplingKind, owner any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.owners[kind][owner]; !ok {
+		return
+	}
+	delete(s.owners[kind], owner)
+
+	if len(s.owners[kind]) == 0 {
+		s.set(kind, s.saved[kind])
+		return
+	}
+	s.apply(kind)
+}
+
+// apply sets the finest rate requested for a sampling
+// Must be called with s.mu held
+func (s *runtimeSampling) apply(kind samplingKind) {
+	finest := 0
+	for _, rate := range s.owners[kind] {
+		if finest == 0 || rate < finest {
+			finest = rate
+		}
+	}
+	s.set(kind, finest)
+}
+
+// current returns the rate in effect for a sampling
+// Must be called with s.mu held
+func (s *runtimeSampling) current(kind samplingKind) int {
+	switch kind {
+	case mutexSampling:
+		return runtime.SetMutexProfileFraction(-1)
+	case blockSampling:
+		return s.blockRate
+	default:
+		return runtime.MemProfileRate
+	}
+}
+
+// set changes the rate of a sampling
+// Must be called with s.mu held
+func (s *runtimeSampling) set(kind samplingKind, rate int) {
+	switch kind {
+	case mutexSampling:
+		runtime.SetMutexProfileFraction(rate)
+	case blockSampling:
+		runtime.SetBlockProfileRate(rate)
+		s.blockRate = rate
+	default:
+		runtime.MemProfileRate = rate
+	}
+}
+
+// setRuntimeProfiles makes the owner's runtime sampling match the given profile types: the sampling
+// they depend on is enabled at the configured rates, divided by samplingReductionFactor if reduced
+// is set, and any other sampling the owner enabled is released
+func setRuntimeProfiles(owner any, rates samplingRates, profileTypes []core.ProfileType, reduced bool) {
+	factor := 1
+	if reduced {
+		factor = samplingReductionFactor
+	}
+
+	needed := make(map[samplingKind]int)
+	for _, profileType := range profileTypes {
+		switch profileType {
+		case core.ProfileMutexCount, core.ProfileMutexDuration:
+			needed[mutexSampling] = rates.mutexFraction * factor
+		case core.ProfileBlockCount, core.ProfileBlockDuration:
+			needed[blockSampling] = rates.blockRate * factor
+		case core.ProfileAllocObjects, core.ProfileAllocSpace, core.ProfileInuseObjects, core.ProfileInuseSpace:
+			if rates.memRate > 0 {
+				needed[memSampling] = rates.memRate
+			}
+		}
+	}
+
+	for _, kind := range []samplingKind{mutexSampling, blockSampling, memSampling} {
+		if rate, ok := needed[kind]; ok {
+			sampling.enable(kind, owner, rate)
+		} else {
+			sampling.disable(kind, owner)
+		}
+	}
+}
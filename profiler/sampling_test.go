@@ -0,0 +1,148 @@
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+func TestNewSamplingRates(t *testing.T) {
+	rates := newSamplingRates(config.Config{MemProfileRate: 4096, BlockProfileRate: time.Millisecond})
+	expected := samplingRates{cpuHz: 100, memRate: 4096, mutexFraction: 10, blockRate: int(time.Millisecond)}
+	if rates != expected {
+		t.Errorf("Expected %+v, got %+v", expected, rates)
+	}
+}
+
+func TestRuntimeSamplingRestoresPreviousRates(t *testing.T) {
+	previousMutex := runtime.SetMutexProfileFraction(3)
+	previousMem := runtime.MemProfileRate
+	runtime.MemProfileRate = 1024
+	defer func() {
+		runtime.SetMutexProfileFraction(previousMutex)
+		runtime.MemProfileRate = previousMem
+	}()
+
+	first, second := new(int), new(int)
+	types := []core.ProfileType{core.ProfileMutexCount, core.ProfileAllocSpace}
+	setRuntimeProfiles(first, samplingRates{mutexFraction: 10, memRate: 4096}, types, false)
+	setRuntimeProfiles(second, samplingRates{mutexFraction: 5, memRate: 8192}, types, false)
+
+	// The finest rate requested is in effect
+	if fraction := runtime.SetMutexProfileFraction(-1); fraction != 5 {
+		t.Errorf("Expected mutex fraction 5, got %d", fraction)
+	}
+	if runtime.MemProfileRate != 4096 {
+		t.Errorf("Expected memory profile rate 4096, got %d", runtime.MemProfileRate)
+	}
+
+	// Reduced sampling samples fewer events
+	setRuntimeProfiles(second, samplingRates{mutexFraction: 5, memRate: 8192}, types, true)
+	if fraction := runtime.SetMutexProfileFraction(-1); fraction != 10 {
+		t.Errorf("Expected mutex fraction 10 with the second profiler reduced, got %d", fraction)
+	}
+
+	setRuntimeProfiles(first, samplingRates{}, nil, false)
+	if fraction := runtime.SetMutexProfileFraction(-1); fraction != 50 {
+		t.Errorf("Expected the remaining profiler's mutex fraction 50, got %d", fraction)
+	}
+
+	// The rates in effect before profiling are restored once no profiler needs them
+	setRuntimeProfiles(second, samplingRates{}, nil, false)
+	if fraction := runtime.SetMutexProfileFraction(-1); fraction != 3 {
+		t.Errorf("Expected the previous mutex fraction 3, got %d", fraction)
+	}
+	if runtime.MemProfileRate != 1024 {
+		t.Errorf("Expected the previous memory profile rate 1024, got %d", runtime.MemProfileRate)
+	}
+}
+
+func TestPprofProfilerPauseRestoresRates(t *testing.T) {
+	previous := runtime.SetMutexProfileFraction(3)
+	defer runtime.SetMutexProfileFraction(previous)
+
+	profiler, err := NewPprofProfiler(config.Config{
+		ApplicationName:      "test-app",
+		OutputDir:            t.TempDir(),
+		ProfileTypes:         []core.ProfileType{core.ProfileMutexCount},
+		MutexProfileFraction: 7,
+		MemoryLimitMB:        1024,
+		SnapshotInterval:     time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewPprofProfiler returned error: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := profiler.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	defer profiler.Stop(ctx)
+	if fraction := runtime.SetMutexProfileFraction(-1); fraction != 7 {
+		t.Errorf("Expected the configured mutex fraction 7, got %d", fraction)
+	}
+
+	if err := profiler.Pause(ctx); err != nil {
+		t.Fatalf("Pause returned error: %v", err)
+	}
+	if fraction := runtime.SetMutexProfileFraction(-1); fraction != 3 {
+		t.Errorf("Expected the previous mutex fraction 3 while paused, got %d", fraction)
+	}
+
+	if err := profiler.Resume(ctx); err != nil {
+		t.Fatalf("Resume returned error: %v", err)
+	}
+	if fraction := runtime.SetMutexProfileFraction(-1); fraction != 7 {
+		t.Errorf("Expected the configured mutex fraction 7 after Resume, got %d", fraction)
+	}
+}
+
+func TestCPUProfileRateWarnsOnce(t *testing.T) {
+	// The runtime writes its warning straight to stderr, so the rotations run in a child process
+	if os.Getenv("PROFILEGO_TEST_CPU_RATE") != "" {
+		dir := t.TempDir()
+		profiler, err := NewPprofProfiler(config.Config{
+			ApplicationName:  "test-app",
+			OutputDir:        dir,
+			ProfileTypes:     []core.ProfileType{core.ProfileCPU},
+			CPUProfileRate:   250,
+			MemoryLimitMB:    1024,
+			SnapshotInterval: 100 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("NewPprofProfiler returned error: %v", err)
+		}
+		if err := profiler.Start(context.Background()); err != nil {
+			t.Fatalf("Start returned error: %v", err)
+		}
+		time.Sleep(350 * time.Millisecond)
+		if err := profiler.Stop(context.Background()); err != nil {
+			t.Fatalf("Stop returned error: %v", err)
+		}
+		if matches, _ := filepath.Glob(filepath.Join(dir, "test-app_cpu_*.pprof")); len(matches) < 3 {
+			t.Fatalf("Expected at least two rotations, got %v", matches)
+		}
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCPUProfileRateWarnsOnce$")
+	cmd.Env = append(os.Environ(), "PROFILEGO_TEST_CPU_RATE=1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if out, err := cmd.Output(); err != nil {
+		t.Fatalf("Child process failed: %v\n%s%s", err, out, stderr.String())
+	}
+
+	if count := strings.Count(stderr.String(), "cannot set cpu profile rate"); count > 1 {
+		t.Errorf("Expected the runtime warning at most once, got it %d times", count)
+	}
+}
@@ -0,0 +1,38 @@
+package profiler
+
+import (
+	"slices"
+
+	"github.com/wasilak/profilego/core"
+)
+
+// suspension tracks the profile types suspended at runtime, e.g. by the memory guard
+type suspension map[core.ProfileType]bool
+
+// active returns the configured profile types that are not suspended
+func (s suspension) active(configured []core.ProfileType) []core.ProfileType {
+	active := make([]core.ProfileType, 0, len(configured))
+	for _, pt := range configured {
+		if !s[pt] {
+			active = append(active, pt)
+		}
+	}
+	return active
+}
+
+// set suspends or resumes the given profile types and returns the configured ones whose state changed
+func (s suspension) set(configured, types []core.ProfileType, suspended bool) []core.ProfileType {
+	var changed []core.ProfileType
+	for _, pt := range types {
+		if s[pt] == suspended || !slices.Contains(configured, pt) {
+			continue
+		}
+		if suspended {
+			s[pt] = true
+		} else {
+			delete(s, pt)
+		}
+		changed = append(changed, pt)
+	}
+	return changed
+}
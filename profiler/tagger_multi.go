@@ -0,0 +1,83 @@
+package profiler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/wasilak/profilego/core"
+)
+
+// MultiTagger implements the Tagger interface by applying tags through several taggers,
+// so tags reach every backend run side by side
+type MultiTagger struct {
+	taggers []core.Tagger
+}
+
+// NewMultiTagger creates a tagger applying tags through all the given taggers in order
+func NewMultiTagger(taggers ...core.Tagger) *MultiTagger {
+	return &MultiTagger{taggers: taggers}
+}
+
+// AddTag adds the tag through every tagger in order, passing on the context each returns
+// and reporting all errors
+func (mt *MultiTagger) AddTag(ctx context.Context, key, value string) (context.Context, error) {
+	var errs []error
+	for _, tagger := range mt.taggers {
+		labelled, err := tagger.AddTag(ctx, key, value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ctx = labelled
+	}
+	return ctx, errors.Join(errs...)
+}
+
+// AddTagScoped adds the tag through every tagger like AddTag; release releases them in reverse order
+func (mt *MultiTagger) AddTagScoped(ctx context.Context, key, value string) (context.Context, func(), error) {
+	var errs []error
+	releases := make([]func(), 0, len(mt.taggers))
+	for _, tagger := range mt.taggers {
+		labelled, release, err := tagger.AddTagScoped(ctx, key, value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ctx = labelled
+		releases = append(releases, release)
+	}
+
+	release := func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+	return ctx, release, errors.Join(errs...)
+}
+
+// TagWrapper executes fn once, nested inside the TagWrapper of every tagger
+// so the tags of all of them are applied during its execution
+func (mt *MultiTagger) TagWrapper(ctx context.Context, key, value string, fn func(context.Context) error) error {
+	return mt.nest(ctx, fn, func(tagger core.Tagger, c context.Context, next func(context.Context) error) error {
+		return tagger.TagWrapper(c, key, value, next)
+	})
+}
+
+// TagWrapperLabels executes fn once, nested inside the TagWrapperLabels of every tagger
+func (mt *MultiTagger) TagWrapperLabels(ctx context.Context, tags core.Tags, fn func(context.Context) error) error {
+	return mt.nest(ctx, fn, func(tagger core.Tagger, c context.Context, next func(context.Context) error) error {
+		return tagger.TagWrapperLabels(c, tags, next)
+	})
+}
+
+// nest runs fn wrapped by every tagger in order, the first tagger outermost
+func (mt *MultiTagger) nest(ctx context.Context, fn func(context.Context) error, wrap func(core.Tagger, context.Context, func(context.Context) error) error) error {
+	wrapped := fn
+	for i := len(mt.taggers) - 1; i >= 0; i-- {
+		tagger, next := mt.taggers[i], wrapped
+		wrapped = func(c context.Context) error {
+			return wrap(tagger, c, next)
+		}
+	}
+	return wrapped(ctx)
+}
@@ -0,0 +1,169 @@
+package profiler
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/wasilak/profilego/core"
+)
+
+// TagPolicyEnforcer applies a core.TagPolicy to tags, remembering the distinct values seen per key
+// and counting the tags it drops or collapses. It is safe for concurrent use.
+type TagPolicyEnforcer struct {
+	policy   core.TagPolicy
+	overflow string
+
+	mu        sync.Mutex
+	seen      map[string]map[string]bool // distinct values kept per limited key
+	dropped   map[string]int64
+	collapsed map[string]int64
+}
+
+// NewTagPolicyEnforcer creates an enforcer for the given policy
+func NewTagPolicyEnforcer(policy core.TagPolicy) *TagPolicyEnforcer {
+	overflow := policy.OverflowValue
+	if overflow == "" {
+		overflow = core.DefaultOverflowValue
+	}
+	return &TagPolicyEnforcer{
+		policy:    policy,
+		overflow:  overflow,
+		seen:      make(map[string]map[string]bool),
+		dropped:   make(map[string]int64),
+		collapsed: make(map[string]int64),
+	}
+}
+
+// Apply returns the tags allowed by the policy, with their values normalized and the values
+// over the limit of their key replaced with the overflow value
+func (e *TagPolicyEnforcer) Apply(tags core.Tags) core.Tags {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	applied := make(core.Tags, 0, len(tags))
+	for _, tag := range tags {
+		if !e.allowed(tag.Key) {
+			e.dropped[tag.Key]++
+			continue
+		}
+
+		for _, normalize := range e.policy.Normalizers[tag.Key] {
+			tag.Value = normalize(tag.Value)
+		}
+		tag.Value = e.limit(tag.Key, tag.Value)
+		applied = append(applied, tag)
+	}
+	return applied
+}
+
+// ApplyMap applies the policy to static tags like Apply, in key order, and returns the tags it keeps
+func (e *TagPolicyEnforcer) ApplyMap(tags map[string]string) map[string]string {
+	if tags == nil {
+		return nil
+	}
+
+	ordered := make(core.Tags, 0, len(tags))
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		ordered = append(ordered, core.Tag{Key: key, Value: tags[key]})
+	}
+
+	applied := make(map[string]string, len(tags))
+	for _, tag := range e.Apply(ordered) {
+		applied[tag.Key] = tag.Value
+	}
+	return applied
+}
+
+// Stats returns the number of tags dropped and collapsed so far, per key
+func (e *TagPolicyEnforcer) Stats() core.TagPolicyStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return core.TagPolicyStats{
+		Dropped:   maps.Clone(e.dropped),
+		Collapsed: maps.Clone(e.collapsed),
+	}
+}
+
+// allowed returns whether tags with the key pass AllowKeys and DenyKeys
+func (e *TagPolicyEnforcer) allowed(key string) bool {
+	if len(e.policy.AllowKeys) > 0 && !slices.Contains(e.policy.AllowKeys, key) {
+		return false
+	}
+	return !slices.Contains(e.policy.DenyKeys, key)
+}
+
+// limit returns the value, or the overflow value once the key reached its number of distinct values
+// Must be called with e.mu held
+func (e *TagPolicyEnforcer) limit(key, value string) string {
+	max := e.policy.Limit(key)
+	if max <= 0 || value == e.overflow {
+		return value
+	}
+
+	values := e.seen[key]
+	if values[value] {
+		return value
+	}
+	if len(values) >= max {
+		e.collapsed[key]++
+		return e.overflow
+	}
+
+	if values == nil {
+		values = make(map[string]bool)
+		e.seen[key] = values
+	}
+	values[value] = true
+	return value
+}
+
+// PolicyTagger implements the Tagger interface by applying a tag policy before passing tags on
+// to another tagger. Tags dropped by the policy are not applied; functions still run.
+type PolicyTagger struct {
+	tagger   core.Tagger
+	enforcer *TagPolicyEnforcer
+}
+
+// NewPolicyTagger creates a tagger applying tags allowed by enforcer through tagger
+func NewPolicyTagger(tagger core.Tagger, enforcer *TagPolicyEnforcer) *PolicyTagger {
+	return &PolicyTagger{tagger: tagger, enforcer: enforcer}
+}
+
+// AddTag adds the tag through the wrapped tagger unless the policy drops it
+func (pt *PolicyTagger) AddTag(ctx context.Context, key, value string) (context.Context, error) {
+	tags := pt.enforcer.Apply(core.NewTags(key, value))
+	if len(tags) == 0 {
+		return ctx, nil
+	}
+	return pt.tagger.AddTag(ctx, tags[0].Key, tags[0].Value)
+}
+
+// AddTagScoped adds the tag through the wrapped tagger unless the policy drops it
+func (pt *PolicyTagger) AddTagScoped(ctx context.Context, key, value string) (context.Context, func(), error) {
+	tags := pt.enforcer.Apply(core.NewTags(key, value))
+	if len(tags) == 0 {
+		return ctx, func() {}, nil
+	}
+	return pt.tagger.AddTagScoped(ctx, tags[0].Key, tags[0].Value)
+}
+
+// TagWrapper executes fn with the tag applied through the wrapped tagger unless the policy drops it
+func (pt *PolicyTagger) TagWrapper(ctx context.Context, key, value string, fn func(context.Context) error) error {
+	tags := pt.enforcer.Apply(core.NewTags(key, value))
+	if len(tags) == 0 {
+		return fn(ctx)
+	}
+	return pt.tagger.TagWrapper(ctx, tags[0].Key, tags[0].Value, fn)
+}
+
+// TagWrapperLabels executes fn with the tags the policy allows applied through the wrapped tagger
+func (pt *PolicyTagger) TagWrapperLabels(ctx context.Context, tags core.Tags, fn func(context.Context) error) error {
+	tags = pt.enforcer.Apply(tags)
+	if len(tags) == 0 {
+		return fn(ctx)
+	}
+	return pt.tagger.TagWrapperLabels(ctx, tags, fn)
+}
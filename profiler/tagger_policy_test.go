@@ -0,0 +1,140 @@
+package profiler
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wasilak/profilego/core"
+)
+
+func TestTagPolicyEnforcer(t *testing.T) {
+	enforcer := NewTagPolicyEnforcer(core.TagPolicy{
+		MaxValuesPerKey: 2,
+		MaxValues:       map[string]int{"method": 0},
+		DenyKeys:        []string{"session"},
+		Normalizers: map[string][]core.TagNormalizer{
+			"route":  {core.NormalizePath},
+			"method": {core.Lowercase},
+		},
+	})
+
+	applied := enforcer.Apply(core.NewTags("route", "/users/1", "session", "abc", "method", "GET"))
+	expected := core.NewTags("route", "/users/:id", "method", "get")
+	if !slices.Equal(applied, expected) {
+		t.Errorf("Expected %v, got %v", expected, applied)
+	}
+
+	// Normalized values count once; new values over the limit collapse into the overflow bucket
+	var values []string
+	for _, route := range []string{"/users/2", "/orders", "/health", "/users/3", "/metrics"} {
+		values = append(values, enforcer.Apply(core.NewTags("route", route))[0].Value)
+	}
+	expectedValues := []string{"/users/:id", "/orders", "other", "/users/:id", "other"}
+	if !slices.Equal(values, expectedValues) {
+		t.Errorf("Expected %v, got %v", expectedValues, values)
+	}
+
+	// Keys without a limit are never collapsed
+	for _, method := range []string{"POST", "PUT", "DELETE"} {
+		if tags := enforcer.Apply(core.NewTags("method", method)); tags[0].Value != strings.ToLower(method) {
+			t.Errorf("Expected method %s to be kept, got %v", method, tags)
+		}
+	}
+
+	stats := enforcer.Stats()
+	if !maps.Equal(stats.Dropped, map[string]int64{"session": 1}) {
+		t.Errorf("Expected one dropped session tag, got %v", stats.Dropped)
+	}
+	if !maps.Equal(stats.Collapsed, map[string]int64{"route": 2}) {
+		t.Errorf("Expected two collapsed route tags, got %v", stats.Collapsed)
+	}
+}
+
+func TestTagPolicyEnforcerAllowKeys(t *testing.T) {
+	enforcer := NewTagPolicyEnforcer(core.TagPolicy{
+		AllowKeys:       []string{"route", "user_id"},
+		DenyKeys:        []string{"user_id"},
+		MaxValuesPerKey: 1,
+		OverflowValue:   "_overflow",
+	})
+
+	applied := enforcer.Apply(core.NewTags("route", "/a", "user_id", "1", "region", "eu", "route", "/b"))
+	if len(applied) != 1 || applied[0] != (core.Tag{Key: "route", Value: "/b"}) {
+		t.Errorf("Expected only the route tag, got %v", applied)
+	}
+
+	applied = enforcer.Apply(core.NewTags("route", "/c"))
+	if applied[0].Value != "_overflow" {
+		t.Errorf("Expected the configured overflow value, got %v", applied)
+	}
+
+	stats := enforcer.Stats()
+	if !maps.Equal(stats.Dropped, map[string]int64{"user_id": 1, "region": 1}) {
+		t.Errorf("Expected user_id and region to be dropped, got %v", stats.Dropped)
+	}
+}
+
+func TestTagPolicyEnforcerApplyMap(t *testing.T) {
+	enforcer := NewTagPolicyEnforcer(core.TagPolicy{
+		MaxValues:   map[string]int{"region": 1},
+		DenyKeys:    []string{"host"},
+		Normalizers: map[string][]core.TagNormalizer{"env": {core.Lowercase}},
+	})
+
+	applied := enforcer.ApplyMap(map[string]string{"env": "PROD", "host": "web-1", "region": "eu"})
+	if !maps.Equal(applied, map[string]string{"env": "prod", "region": "eu"}) {
+		t.Errorf("Expected the denied tag dropped and env normalized, got %v", applied)
+	}
+
+	// Static tags count towards the limit of their key like dynamic ones
+	if tags := enforcer.Apply(core.NewTags("region", "us")); tags[0].Value != core.DefaultOverflowValue {
+		t.Errorf("Expected a second region to collapse, got %v", tags)
+	}
+	if enforcer.ApplyMap(nil) != nil {
+		t.Error("Expected nil tags to stay nil")
+	}
+}
+
+func TestPolicyTagger(t *testing.T) {
+	var log []string
+	tagger := NewPolicyTagger(&recordingTagger{name: "a", log: &log}, NewTagPolicyEnforcer(core.TagPolicy{
+		DenyKeys:    []string{"user_id"},
+		Normalizers: map[string][]core.TagNormalizer{"route": {core.NormalizePath}},
+	}))
+	ctx := context.Background()
+
+	if _, err := tagger.AddTag(ctx, "route", "/users/1"); err != nil {
+		t.Fatalf("AddTag returned error: %v", err)
+	}
+	if got, err := tagger.AddTag(ctx, "user_id", "1"); err != nil || got != ctx {
+		t.Fatalf("Expected a dropped tag to return ctx unchanged, got %v, %v", got, err)
+	}
+	_, release, err := tagger.AddTagScoped(ctx, "user_id", "1")
+	if err != nil {
+		t.Fatalf("AddTagScoped returned error: %v", err)
+	}
+	release()
+
+	calls := 0
+	fn := func(c context.Context) error {
+		calls++
+		return nil
+	}
+	if err := tagger.TagWrapper(ctx, "user_id", "1", fn); err != nil {
+		t.Fatalf("TagWrapper returned error: %v", err)
+	}
+	if err := tagger.TagWrapperLabels(ctx, core.NewTags("user_id", "1", "route", "/users/2"), fn); err != nil {
+		t.Fatalf("TagWrapperLabels returned error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected fn to run for every call, ran %d times", calls)
+	}
+
+	expected := []string{"a route=/users/:id", "a enter route=/users/:id", "a exit"}
+	if strings.Join(log, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, log)
+	}
+}
@@ -2,10 +2,13 @@ package profiler
 
 import (
 	"context"
+	"runtime/pprof"
+
+	"github.com/wasilak/profilego/core"
 )
 
-// PprofTagger implements the Tagger interface for pprof backend
-// Note: pprof doesn't support runtime tagging like Pyroscope, so this is a no-op implementation
+// PprofTagger implements the Tagger interface for pprof backend using runtime/pprof labels,
+// which CPU and goroutine profiles record with every sample
 type PprofTagger struct{}
 
 // NewPprofTagger creates a new pprof tagger
@@ -13,16 +16,46 @@ func NewPprofTagger() *PprofTagger {
 	return &PprofTagger{}
 }
 
-// AddTag is a no-op for pprof as it doesn't support runtime tagging
-func (pt *PprofTagger) AddTag(key, value string) error {
-	// pprof doesn't support runtime tagging, so this is a no-op
-	// Users can use build flags or comments for static profiling labels
-	return nil
+// AddTag adds a pprof label to the labels of ctx and sets them on the calling goroutine
+func (pt *PprofTagger) AddTag(ctx context.Context, key, value string) (context.Context, error) {
+	return setGoroutineLabels(ctx, core.NewTags(key, value)), nil
+}
+
+// AddTagScoped adds a pprof label like AddTag; release restores the labels of ctx on the calling goroutine
+func (pt *PprofTagger) AddTagScoped(ctx context.Context, key, value string) (context.Context, func(), error) {
+	labelled, release := setGoroutineLabelsScoped(ctx, core.NewTags(key, value))
+	return labelled, release, nil
 }
 
-// TagWrapper executes the function without adding tags (pprof limitation)
-// For pprof, we just execute the function and return its error
+// TagWrapper executes a function with an additional pprof label
+// The label is only applied during the execution of the function
 func (pt *PprofTagger) TagWrapper(ctx context.Context, key, value string, fn func(context.Context) error) error {
-	// pprof doesn't support runtime tagging, so we just execute the function with context
-	return fn(ctx)
+	return pt.TagWrapperLabels(ctx, core.NewTags(key, value), fn)
+}
+
+// TagWrapperLabels executes a function with several additional pprof labels,
+// merged with the labels already present in the context
+func (pt *PprofTagger) TagWrapperLabels(ctx context.Context, tags core.Tags, fn func(context.Context) error) error {
+	var err error
+	pprof.Do(ctx, pprof.Labels(tags.KeyValues()...), func(c context.Context) {
+		err = fn(c)
+	})
+	return err
+}
+
+// setGoroutineLabels adds tags to the pprof labels of ctx and sets the result on the calling goroutine.
+// The runtime does not expose the labels of a goroutine, so the ones of ctx are used as the base.
+func setGoroutineLabels(ctx context.Context, tags core.Tags) context.Context {
+	labelled := pprof.WithLabels(ctx, pprof.Labels(tags.KeyValues()...))
+	pprof.SetGoroutineLabels(labelled)
+	return labelled
+}
+
+// setGoroutineLabelsScoped sets labels like setGoroutineLabels and returns a function
+// setting the labels of ctx on the calling goroutine again
+func setGoroutineLabelsScoped(ctx context.Context, tags core.Tags) (context.Context, func()) {
+	labelled := setGoroutineLabels(ctx, tags)
+	return labelled, func() {
+		pprof.SetGoroutineLabels(ctx)
+	}
 }
@@ -5,6 +5,8 @@ import (
 	"runtime/pprof"
 
 	"github.com/grafana/pyroscope-go"
+
+	"github.com/wasilak/profilego/core"
 )
 
 // PyroscopeTagger implements the Tagger interface for Pyroscope backend
@@ -15,19 +17,28 @@ func NewPyroscopeTagger() *PyroscopeTagger {
 	return &PyroscopeTagger{}
 }
 
-// AddTag adds a key-value pair tag to the current Pyroscope profiling context
-func (pt *PyroscopeTagger) AddTag(key, value string) error {
-	labels := pprof.Labels(key, value)
-	pyroscope.TagWrapper(context.Background(), labels, func(ctx context.Context) {
-		// No-op function, just adding the tag
-	})
-	return nil
+// AddTag adds a Pyroscope profiling tag to the labels of ctx and sets them on the calling goroutine.
+// Pyroscope records the runtime/pprof labels of every sample, so no call into its client is needed.
+func (pt *PyroscopeTagger) AddTag(ctx context.Context, key, value string) (context.Context, error) {
+	return setGoroutineLabels(ctx, core.NewTags(key, value)), nil
+}
+
+// AddTagScoped adds a Pyroscope profiling tag like AddTag; release restores the labels of ctx on the calling goroutine
+func (pt *PyroscopeTagger) AddTagScoped(ctx context.Context, key, value string) (context.Context, func(), error) {
+	labelled, release := setGoroutineLabelsScoped(ctx, core.NewTags(key, value))
+	return labelled, release, nil
 }
 
 // TagWrapper executes a function with additional Pyroscope profiling tags
 // The tags are only applied during the execution of the function
 func (pt *PyroscopeTagger) TagWrapper(ctx context.Context, key, value string, fn func(context.Context) error) error {
-	labels := pprof.Labels(key, value)
+	return pt.TagWrapperLabels(ctx, core.NewTags(key, value), fn)
+}
+
+// TagWrapperLabels executes a function with several additional Pyroscope profiling tags,
+// merged with the labels already present in the context
+func (pt *PyroscopeTagger) TagWrapperLabels(ctx context.Context, tags core.Tags, fn func(context.Context) error) error {
+	labels := pprof.Labels(tags.KeyValues()...)
 	var err error
 	pyroscope.TagWrapper(ctx, labels, func(c context.Context) {
 		err = fn(c)
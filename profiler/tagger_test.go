@@ -2,18 +2,36 @@ package profiler
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
 )
 
-// TestPyroscopeTaggerAddTag tests adding a tag to Pyroscope profiler
+// TestPyroscopeTaggerAddTag tests that the tag is added to the labels of the context
 func TestPyroscopeTaggerAddTag(t *testing.T) {
 	tagger := NewPyroscopeTagger()
-	
-	// AddTag should not return an error
-	err := tagger.AddTag("test_key", "test_value")
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("service", "api"))
+
+	ctx, err := tagger.AddTag(ctx, "test_key", "test_value")
 	if err != nil {
 		t.Fatalf("AddTag returned error: %v", err)
 	}
+	defer pprof.SetGoroutineLabels(context.Background())
+
+	if value, _ := pprof.Label(ctx, "test_key"); value != "test_value" {
+		t.Errorf("Expected the context to carry test_key=test_value, got %q", value)
+	}
+	if value, _ := pprof.Label(ctx, "service"); value != "api" {
+		t.Errorf("Expected the context to keep service=api, got %q", value)
+	}
 }
 
 // TestPyroscopeTaggerTagWrapper tests TagWrapper with Pyroscope profiler
@@ -36,15 +54,26 @@ func TestPyroscopeTaggerTagWrapper(t *testing.T) {
 	}
 }
 
-// TestPprofTaggerAddTag tests adding a tag to pprof profiler (no-op)
+// TestPprofTaggerAddTag tests that the tag is added to the labels of the context
 func TestPprofTaggerAddTag(t *testing.T) {
 	tagger := NewPprofTagger()
-	
-	// AddTag should not return an error (it's a no-op for pprof)
-	err := tagger.AddTag("test_key", "test_value")
+
+	ctx, err := tagger.AddTag(context.Background(), "test_key", "test_value")
 	if err != nil {
 		t.Fatalf("AddTag returned error: %v", err)
 	}
+	defer pprof.SetGoroutineLabels(context.Background())
+
+	ctx, err = tagger.AddTag(ctx, "other_key", "other_value")
+	if err != nil {
+		t.Fatalf("AddTag returned error: %v", err)
+	}
+
+	for key, expected := range map[string]string{"test_key": "test_value", "other_key": "other_value"} {
+		if value, _ := pprof.Label(ctx, key); value != expected {
+			t.Errorf("Expected the context to carry %s=%s, got %q", key, expected, value)
+		}
+	}
 }
 
 // TestPprofTaggerTagWrapper tests TagWrapper with pprof profiler
@@ -66,3 +95,289 @@ func TestPprofTaggerTagWrapper(t *testing.T) {
 		t.Fatal("function was not executed inside TagWrapper")
 	}
 }
+
+// recordingTagger records the tags applied through it
+type recordingTagger struct {
+	name string
+	log  *[]string
+}
+
+func (rt *recordingTagger) AddTag(ctx context.Context, key, value string) (context.Context, error) {
+	*rt.log = append(*rt.log, rt.name+" "+key+"="+value)
+	return context.WithValue(ctx, rt, value), nil
+}
+
+func (rt *recordingTagger) AddTagScoped(ctx context.Context, key, value string) (context.Context, func(), error) {
+	ctx, _ = rt.AddTag(ctx, key, value)
+	return ctx, func() { *rt.log = append(*rt.log, rt.name+" release") }, nil
+}
+
+func (rt *recordingTagger) TagWrapper(ctx context.Context, key, value string, fn func(context.Context) error) error {
+	*rt.log = append(*rt.log, rt.name+" enter")
+	err := fn(ctx)
+	*rt.log = append(*rt.log, rt.name+" exit")
+	return err
+}
+
+func (rt *recordingTagger) TagWrapperLabels(ctx context.Context, tags core.Tags, fn func(context.Context) error) error {
+	*rt.log = append(*rt.log, rt.name+" enter "+strings.Join(tags.KeyValues(), "="))
+	err := fn(ctx)
+	*rt.log = append(*rt.log, rt.name+" exit")
+	return err
+}
+
+// TestMultiTagger tests that tags are applied through every tagger
+func TestMultiTagger(t *testing.T) {
+	var log []string
+	tagger := NewMultiTagger(&recordingTagger{name: "a", log: &log}, &recordingTagger{name: "b", log: &log})
+
+	if _, err := tagger.AddTag(context.Background(), "k", "v"); err != nil {
+		t.Fatalf("AddTag returned error: %v", err)
+	}
+
+	calls := 0
+	err := tagger.TagWrapper(context.Background(), "k", "v", func(c context.Context) error {
+		calls++
+		log = append(log, "fn")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TagWrapper returned error: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("function should run once, ran %d times", calls)
+	}
+
+	expected := []string{"a k=v", "b k=v", "a enter", "b enter", "fn", "b exit", "a exit"}
+	if strings.Join(log, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, log)
+	}
+}
+
+// TestMultiTaggerAddTagScoped tests that the context is passed through every tagger and released in reverse order
+func TestMultiTaggerAddTagScoped(t *testing.T) {
+	var log []string
+	a, b := &recordingTagger{name: "a", log: &log}, &recordingTagger{name: "b", log: &log}
+	tagger := NewMultiTagger(a, b)
+
+	ctx, release, err := tagger.AddTagScoped(context.Background(), "k", "v")
+	if err != nil {
+		t.Fatalf("AddTagScoped returned error: %v", err)
+	}
+	if ctx.Value(a) != "v" || ctx.Value(b) != "v" {
+		t.Error("Expected the context returned by every tagger to be passed on")
+	}
+	release()
+
+	expected := []string{"a k=v", "b k=v", "b release", "a release"}
+	if strings.Join(log, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, log)
+	}
+}
+
+// TestPyroscopeTaggerTagWrapperLabels tests that the tags are merged with the labels of the context
+func TestPyroscopeTaggerTagWrapperLabels(t *testing.T) {
+	tagger := NewPyroscopeTagger()
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("service", "api", "route", "/"))
+
+	labels := map[string]string{}
+	err := tagger.TagWrapperLabels(ctx, core.NewTags("route", "/users", "method", "GET"), func(c context.Context) error {
+		pprof.ForLabels(c, func(key, value string) bool {
+			labels[key] = value
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TagWrapperLabels returned error: %v", err)
+	}
+
+	expected := map[string]string{"service": "api", "route": "/users", "method": "GET"}
+	if len(labels) != len(expected) {
+		t.Fatalf("Expected labels %v, got %v", expected, labels)
+	}
+	for key, value := range expected {
+		if labels[key] != value {
+			t.Errorf("Expected %s=%s, got %v", key, value, labels)
+		}
+	}
+}
+
+// TestMultiTaggerTagWrapperLabels tests that all tags are applied through every tagger at once
+func TestMultiTaggerTagWrapperLabels(t *testing.T) {
+	var log []string
+	tagger := NewMultiTagger(&recordingTagger{name: "a", log: &log}, &recordingTagger{name: "b", log: &log})
+
+	err := tagger.TagWrapperLabels(context.Background(), core.NewTags("k1", "v1", "k2", "v2"), func(c context.Context) error {
+		log = append(log, "fn")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TagWrapperLabels returned error: %v", err)
+	}
+
+	expected := []string{"a enter k1=v1=k2=v2", "b enter k1=v1=k2=v2", "fn", "b exit", "a exit"}
+	if strings.Join(log, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, log)
+	}
+}
+
+// TestPprofTaggerLabelsGoroutineProfile tests that goroutines started with tags carry them in the goroutine profile
+func TestPprofTaggerLabelsGoroutineProfile(t *testing.T) {
+	profiler, dir := startLabelledPprofProfiler(t, core.ProfileGoroutines)
+	tagger := NewPprofTagger()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	started := make(chan struct{}, 2)
+	block := func() {
+		started <- struct{}{}
+		<-release
+	}
+
+	tagger.TagWrapperLabels(context.Background(), core.NewTags("route", "/users", "method", "GET"), func(c context.Context) error {
+		go block()
+		return nil
+	})
+	go func() {
+		tagger.AddTag(context.Background(), "job", "import")
+		go block()
+	}()
+	<-started
+	<-started
+
+	if err := profiler.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+
+	labels := profileLabels(t, dir, "goroutine")
+	if !hasLabels(labels, map[string]string{"route": "/users", "method": "GET"}) {
+		t.Errorf("Expected a goroutine labelled by TagWrapperLabels, got %v", labels)
+	}
+	if !hasLabels(labels, map[string]string{"job": "import"}) {
+		t.Errorf("Expected a goroutine labelled by AddTag, got %v", labels)
+	}
+}
+
+// TestPprofTaggerAddTagScoped tests that releasing a scoped tag restores the previous labels of the goroutine
+func TestPprofTaggerAddTagScoped(t *testing.T) {
+	profiler, dir := startLabelledPprofProfiler(t, core.ProfileGoroutines)
+	tagger := NewPprofTagger()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	started := make(chan struct{}, 2)
+	block := func() {
+		started <- struct{}{}
+		<-release
+	}
+
+	go func() {
+		ctx, _ := tagger.AddTag(context.Background(), "request", "r1")
+		_, releaseTag, _ := tagger.AddTagScoped(ctx, "user", "u1")
+		go block()
+		releaseTag()
+		go block()
+	}()
+	<-started
+	<-started
+
+	if err := profiler.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+
+	labels := profileLabels(t, dir, "goroutine")
+	if !hasLabels(labels, map[string]string{"request": "r1", "user": "u1"}) {
+		t.Errorf("Expected a goroutine started within the scope, got %v", labels)
+	}
+	if !hasLabels(labels, map[string]string{"request": "r1", "user": ""}) {
+		t.Errorf("Expected a goroutine started after the release with the previous labels only, got %v", labels)
+	}
+}
+
+// TestPprofTaggerLabelsCPUProfile tests that CPU samples taken inside TagWrapper carry its tags
+func TestPprofTaggerLabelsCPUProfile(t *testing.T) {
+	profiler, dir := startLabelledPprofProfiler(t, core.ProfileCPU)
+
+	NewPprofTagger().TagWrapper(context.Background(), "work", "spin", func(c context.Context) error {
+		for deadline := time.Now().Add(500 * time.Millisecond); time.Now().Before(deadline); {
+		}
+		return nil
+	})
+
+	if err := profiler.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+
+	if labels := profileLabels(t, dir, "cpu"); !hasLabels(labels, map[string]string{"work": "spin"}) {
+		t.Errorf("Expected CPU samples labelled by TagWrapper, got %v", labels)
+	}
+}
+
+// startLabelledPprofProfiler starts a pprof profiler collecting a single profile type into a temporary directory
+func startLabelledPprofProfiler(t *testing.T, profileType core.ProfileType) (*PprofProfiler, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	profiler, err := NewPprofProfiler(config.Config{
+		ApplicationName:  "test-app",
+		OutputDir:        dir,
+		ProfileTypes:     []core.ProfileType{profileType},
+		MemoryLimitMB:    1024,
+		SnapshotInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewPprofProfiler returned error: %v", err)
+	}
+	if err := profiler.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	t.Cleanup(func() { profiler.Stop(context.Background()) })
+	return profiler, dir
+}
+
+// hasLabels returns whether a sample carries all the expected labels; an empty value expects the label to be unset
+func hasLabels(samples []map[string][]string, expected map[string]string) bool {
+	for _, labels := range samples {
+		matches := true
+		for key, value := range expected {
+			if value == "" {
+				matches = matches && len(labels[key]) == 0
+			} else {
+				matches = matches && slices.Contains(labels[key], value)
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// profileLabels parses the profile of the given type written into dir and returns the labels of every sample
+func profileLabels(t *testing.T, dir, profileType string) []map[string][]string {
+	t.Helper()
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "test-app_"+profileType+"_*.pprof"))
+	if len(matches) != 1 {
+		t.Fatalf("Expected one %s profile, got %v", profileType, matches)
+	}
+	f, err := os.Open(matches[0])
+	if err != nil {
+		t.Fatalf("Failed to open profile: %v", err)
+	}
+	defer f.Close()
+
+	p, err := profile.Parse(f)
+	if err != nil {
+		t.Fatalf("Failed to parse profile: %v", err)
+	}
+
+	labels := make([]map[string][]string, 0, len(p.Sample))
+	for _, sample := range p.Sample {
+		labels = append(labels, sample.Label)
+	}
+	return labels
+}
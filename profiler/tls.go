@@ -0,0 +1,168 @@
+package profiler
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/wasilak/profilego/config"
+)
+
+const (
+	// uploadTimeout matches the timeout used by the Pyroscope client for uploads
+	uploadTimeout = 30 * time.Second
+
+	// uploadConnections matches the number of Pyroscope upload workers
+	uploadConnections = 5
+)
+
+// newHTTPClient builds the HTTP client used for uploads, honouring the TLS settings
+// and adding the bearer token if one is configured
+func newHTTPClient(cfg config.Config, creds credentials) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxConnsPerHost = uploadConnections
+
+	if cfg.TLSEnabled() {
+		tlsConfig, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	var roundTripper http.RoundTripper = transport
+	if creds.bearerToken != nil {
+		roundTripper = &bearerTransport{base: transport, token: creds.bearerToken}
+	}
+
+	return &http.Client{
+		Transport: roundTripper,
+		Timeout:   uploadTimeout,
+		// Don't follow redirects, the Authorization header would be dropped on the way
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, nil
+}
+
+// newTLSConfig builds the TLS configuration from the CA bundle, client certificate,
+// minimum version and verification settings
+func newTLSConfig(cfg config.Config) (*tls.Config, error) {
+	minVersion, err := config.TLSVersion(cfg.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: minVersion,
+	}
+
+	if cfg.TLSCAPath != "" {
+		pem, err := os.ReadFile(cfg.TLSCAPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.TLSCAPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.TLSCertPath != "" || cfg.TLSKeyPath != "" {
+		reloader, err := newCertReloader(cfg.TLSCertPath, cfg.TLSKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = reloader.GetClientCertificate
+	}
+
+	if cfg.SkipTLSVerify {
+		slog.Warn("profilego - TLS certificate verification is disabled, uploads are vulnerable to interception; do not use in production",
+			"server_address", cfg.ServerAddress)
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	return tlsConfig, nil
+}
+
+// certReloader serves the client certificate, reloading it when the files change on disk
+// so that rotated certificates are picked up without restarting the profiler
+type certReloader struct {
+	mu       sync.Mutex
+	certPath string
+	keyPath  string
+	cert     *tls.Certificate
+	certMod  time.Time
+	keyMod   time.Time
+}
+
+// newCertReloader loads the client certificate and key
+func newCertReloader(certPath, keyPath string) (*certReloader, error) {
+	if certPath == "" || keyPath == "" {
+		return nil, errors.New("TLSCertPath and TLSKeyPath must be set together")
+	}
+
+	r := &certReloader{certPath: certPath, keyPath: keyPath}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetClientCertificate returns the current client certificate, reloading it if the files changed.
+// If reloading fails the previously loaded certificate keeps being used.
+func (r *certReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.changed() {
+		if err := r.reloadLocked(); err != nil {
+			slog.Error("profilego - failed to reload TLS client certificate", "error", err)
+		}
+	}
+	return r.cert, nil
+}
+
+// reload loads the certificate and key from disk
+func (r *certReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked()
+}
+
+// reloadLocked loads the certificate and key from disk
+// Must be called with r.mu held
+func (r *certReloader) reloadLocked() error {
+	certMod, keyMod := modTime(r.certPath), modTime(r.keyPath)
+
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS client certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.certMod, r.keyMod = certMod, keyMod
+	return nil
+}
+
+// changed reports whether the certificate or key file was modified since the last load
+// Must be called with r.mu held
+func (r *certReloader) changed() bool {
+	return !modTime(r.certPath).Equal(r.certMod) || !modTime(r.keyPath).Equal(r.keyMod)
+}
+
+// modTime returns the modification time of path, or the zero time if it cannot be read
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,250 @@
+package profiler
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wasilak/profilego/config"
+	"github.com/wasilak/profilego/core"
+)
+
+// writeClientCert writes a self-signed client certificate with the given common name
+func writeClientCert(t *testing.T, certPath, keyPath, commonName string, modTime time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{certPath, keyPath} {
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// writeServerCA writes the certificate of a httptest TLS server as a CA bundle
+func writeServerCA(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// tlsTestConfig returns a Pyroscope config pointing at the given server
+func tlsTestConfig(server *httptest.Server) config.Config {
+	return config.Config{
+		ApplicationName: "test-app",
+		Backend:         core.PyroscopeBackend,
+		ServerAddress:   server.URL,
+		EnableTLS:       true,
+	}
+}
+
+func TestHTTPClientCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// Without the CA bundle the server certificate is not trusted
+	client, err := newHTTPClient(tlsTestConfig(server), credentials{})
+	if err != nil {
+		t.Fatalf("newHTTPClient returned error: %v", err)
+	}
+	if _, err := client.Get(server.URL); err == nil {
+		t.Error("Expected request to fail without the CA bundle")
+	}
+
+	cfg := tlsTestConfig(server)
+	cfg.TLSCAPath = writeServerCA(t, server)
+	client, err = newHTTPClient(cfg, credentials{})
+	if err != nil {
+		t.Fatalf("newHTTPClient returned error: %v", err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request with CA bundle failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestHTTPClientTLSWithoutEnableTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The CA bundle is honoured for an https address even though EnableTLS is not set
+	cfg := tlsTestConfig(server)
+	cfg.EnableTLS = false
+	cfg.TLSCAPath = writeServerCA(t, server)
+	client, err := newHTTPClient(cfg, credentials{})
+	if err != nil {
+		t.Fatalf("newHTTPClient returned error: %v", err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request with CA bundle failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// An address without a scheme uses https:// when a TLS setting is given
+	cfg.ServerAddress = strings.TrimPrefix(server.URL, "https://")
+	if address, err := formatServerAddressAsURL(cfg.ServerAddress, cfg.TLSEnabled()); err != nil || address != server.URL {
+		t.Errorf("Expected %s, got %s (%v)", server.URL, address, err)
+	}
+}
+
+func TestHTTPClientSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	cfg := tlsTestConfig(server)
+	cfg.SkipTLSVerify = true
+	client, err := newHTTPClient(cfg, credentials{})
+	if err != nil {
+		t.Fatalf("newHTTPClient returned error: %v", err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request with SkipTLSVerify failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestHTTPClientMinVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	cfg := tlsTestConfig(server)
+	cfg.TLSCAPath = writeServerCA(t, server)
+	cfg.TLSMinVersion = "1.3"
+	client, err := newHTTPClient(cfg, credentials{})
+	if err != nil {
+		t.Fatalf("newHTTPClient returned error: %v", err)
+	}
+	if _, err := client.Get(server.URL); err == nil {
+		t.Error("Expected request to fail against a server limited to TLS 1.2")
+	}
+}
+
+func TestHTTPClientReloadsClientCertificate(t *testing.T) {
+	var commonNames []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		commonNames = append(commonNames, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	writeClientCert(t, certPath, keyPath, "first", time.Now().Add(-time.Minute))
+
+	cfg := tlsTestConfig(server)
+	cfg.TLSCAPath = writeServerCA(t, server)
+	cfg.TLSCertPath, cfg.TLSKeyPath = certPath, keyPath
+	client, err := newHTTPClient(cfg, credentials{})
+	if err != nil {
+		t.Fatalf("newHTTPClient returned error: %v", err)
+	}
+
+	get := func() {
+		t.Helper()
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request with client certificate failed: %v", err)
+		}
+		resp.Body.Close()
+		client.CloseIdleConnections()
+	}
+
+	get()
+	writeClientCert(t, certPath, keyPath, "second", time.Now())
+	get()
+
+	if len(commonNames) != 2 || commonNames[0] != "first" || commonNames[1] != "second" {
+		t.Errorf("Expected client certificates [first second], got %v", commonNames)
+	}
+}
+
+func TestNewTLSConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalidCA := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(invalidCA, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]config.Config{
+		"missing CA":      {TLSCAPath: filepath.Join(dir, "missing.pem")},
+		"invalid CA":      {TLSCAPath: invalidCA},
+		"missing key":     {TLSCertPath: invalidCA},
+		"invalid version": {TLSMinVersion: "2.0"},
+	}
+	for name, cfg := range testCases {
+		if _, err := newTLSConfig(cfg); err == nil {
+			t.Errorf("%s: newTLSConfig should return error", name)
+		}
+	}
+}
+
+func TestFormatServerAddressAsURL(t *testing.T) {
+	testCases := []struct {
+		address  string
+		secure   bool
+		expected string
+	}{
+		{"localhost:4040", false, "http://localhost:4040"},
+		{"localhost:4040", true, "https://localhost:4040"},
+		{"http://localhost:4040", true, "http://localhost:4040"},
+		{"https://profiles.example.com", false, "https://profiles.example.com"},
+	}
+
+	for _, tc := range testCases {
+		got, err := formatServerAddressAsURL(tc.address, tc.secure)
+		if err != nil {
+			t.Errorf("formatServerAddressAsURL(%q, %v) returned error: %v", tc.address, tc.secure, err)
+		}
+		if got != tc.expected {
+			t.Errorf("formatServerAddressAsURL(%q, %v) = %q, want %q", tc.address, tc.secure, got, tc.expected)
+		}
+	}
+}
@@ -2,10 +2,12 @@ package profilego
 
 import (
 	"context"
+	"runtime/pprof"
 	"testing"
 
 	"github.com/wasilak/profilego/config"
 	"github.com/wasilak/profilego/core"
+	profiler_pkg "github.com/wasilak/profilego/profiler"
 )
 
 // TestAddTag tests the global AddTag function
@@ -22,11 +24,43 @@ func TestAddTag(t *testing.T) {
 	}
 	defer Stop()
 
-	// AddTag should work without error
-	err = AddTag("test_key", "test_value")
+	// AddTag should return a context carrying the tag
+	ctx, err := AddTag(nil, "test_key", "test_value")
 	if err != nil {
 		t.Fatalf("AddTag returned error: %v", err)
 	}
+	defer pprof.SetGoroutineLabels(context.Background())
+
+	if value, ok := pprof.Label(ctx, "test_key"); !ok || value != "test_value" {
+		t.Fatalf("Expected the context to carry the pprof label, got %q", value)
+	}
+}
+
+// TestAddTagScoped tests the global AddTagScoped function
+func TestAddTagScoped(t *testing.T) {
+	cfg := config.Config{
+		ApplicationName: "test-app",
+		Backend:         core.PprofBackend,
+	}
+
+	_, err := InitWithConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("failed to initialize profiler: %v", err)
+	}
+	defer Stop()
+
+	parent := pprof.WithLabels(context.Background(), pprof.Labels("request_id", "12345"))
+	ctx, release, err := AddTagScoped(parent, "test_key", "test_value")
+	if err != nil {
+		t.Fatalf("AddTagScoped returned error: %v", err)
+	}
+	defer release()
+
+	for key, expected := range map[string]string{"request_id": "12345", "test_key": "test_value"} {
+		if value, _ := pprof.Label(ctx, key); value != expected {
+			t.Errorf("Expected the context to carry %s=%s, got %q", key, expected, value)
+		}
+	}
 }
 
 // TestTagWrapper tests the global TagWrapper function
@@ -65,29 +99,35 @@ func TestTagWrapper(t *testing.T) {
 		t.Fatal("context was not passed to function")
 	}
 
-	if receivedCtx != ctx {
-		t.Fatal("wrong context passed to function")
+	// The pprof backend passes a context derived from ctx carrying the label
+	if value, ok := pprof.Label(receivedCtx, "test_key"); !ok || value != "test_value" {
+		t.Fatalf("Expected the context to carry the pprof label, got %q", value)
 	}
 }
 
 // TestAddTagWithoutInit tests AddTag without initialization
 func TestAddTagWithoutInit(t *testing.T) {
 	// Ensure profiler is not initialized
-	profilerManager = nil
-	globalTagger = nil
+	defaultClient = nil
 
 	// AddTag should return an error
-	err := AddTag("test_key", "test_value")
+	_, err := AddTag(context.Background(), "test_key", "test_value")
 	if err == nil {
 		t.Fatal("AddTag should return error when profiler not initialized")
 	}
+
+	// AddTagScoped should return an error and a release function safe to call
+	_, release, err := AddTagScoped(context.Background(), "test_key", "test_value")
+	if err == nil {
+		t.Fatal("AddTagScoped should return error when profiler not initialized")
+	}
+	release()
 }
 
 // TestTagWrapperWithoutInit tests TagWrapper without initialization
 func TestTagWrapperWithoutInit(t *testing.T) {
 	// Ensure profiler is not initialized
-	profilerManager = nil
-	globalTagger = nil
+	defaultClient = nil
 
 	// TagWrapper should return an error
 	ctx := context.Background()
@@ -99,3 +139,32 @@ func TestTagWrapperWithoutInit(t *testing.T) {
 		t.Fatal("TagWrapper should return error when profiler not initialized")
 	}
 }
+
+// TestNewTaggerCombinesBackends tests that one tagger per backend type is combined
+func TestNewTaggerCombinesBackends(t *testing.T) {
+	single := newTagger(config.Config{Backend: core.PyroscopeBackend})
+	if _, ok := single.(*profiler_pkg.PyroscopeTagger); !ok {
+		t.Errorf("Expected *PyroscopeTagger for a single backend, got %T", single)
+	}
+
+	multi := newTagger(config.Config{Backends: []config.BackendConfig{
+		{Type: core.PyroscopeBackend},
+		{Type: core.PprofBackend},
+		{Name: "archive", Type: core.PprofBackend},
+	}})
+	if _, ok := multi.(*profiler_pkg.MultiTagger); !ok {
+		t.Errorf("Expected *MultiTagger for several backend types, got %T", multi)
+	}
+}
+
+// TestSetGlobalTagWithoutInit tests SetGlobalTag and RemoveGlobalTag without initialization
+func TestSetGlobalTagWithoutInit(t *testing.T) {
+	defaultClient = nil
+
+	if err := SetGlobalTag("role", "leader"); err == nil {
+		t.Fatal("SetGlobalTag should return error when profiler not initialized")
+	}
+	if err := RemoveGlobalTag("role"); err == nil {
+		t.Fatal("RemoveGlobalTag should return error when profiler not initialized")
+	}
+}